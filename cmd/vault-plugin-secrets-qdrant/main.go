@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"os"
 
+	metrics "github.com/armon/go-metrics"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/sdk/plugin"
@@ -20,6 +22,31 @@ func main() {
 		os.Exit(1)
 	}
 
+	// send plugin metrics to statsd, e.g. registered with
+	// -env QDRANT_PLUGIN_STATSD_ADDR=127.0.0.1:8125
+	if addr := os.Getenv("QDRANT_PLUGIN_STATSD_ADDR"); addr != "" {
+		sink, err := metrics.NewStatsdSink(addr)
+		if err != nil {
+			logger.Error("plugin shutting down", "invalid statsd address", err)
+			os.Exit(1)
+		}
+
+		conf := metrics.DefaultConfig("vault")
+		conf.EnableHostname = false
+		metrics.NewGlobal(conf, sink)
+	}
+
+	// export traces via OTLP/gRPC, e.g. registered with
+	// -env QDRANT_PLUGIN_OTLP_ENDPOINT=127.0.0.1:4317
+	if endpoint := os.Getenv("QDRANT_PLUGIN_OTLP_ENDPOINT"); endpoint != "" {
+		shutdown, err := qdrant.SetupTracing(context.Background(), endpoint)
+		if err != nil {
+			logger.Error("plugin shutting down", "invalid otlp endpoint", err)
+			os.Exit(1)
+		}
+		defer shutdown(context.Background())
+	}
+
 	tlsConfig := apiClientMeta.GetTLSConfig()
 	tlsProviderFunc := api.VaultPluginTLSProvider(tlsConfig)
 
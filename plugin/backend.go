@@ -2,11 +2,15 @@ package qdrant
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -17,21 +21,61 @@ type QdrantBackend struct {
 	*framework.Backend
 	clientMutex sync.RWMutex
 	client      *QdrantClient
+	registry    RoleRegistry
+
+	collectionsMutex sync.Mutex
+	collections      map[string]*collectionsCacheEntry
+	collectionsEpoch uint64
+
+	driftMutex   sync.Mutex
+	driftChecked map[string]time.Time
+
+	pruneMutex     sync.Mutex
+	issuancePruned time.Time
+
+	rateLimitLocks []*locksutil.LockEntry
+}
+
+// collectionsCacheEntry holds the collection names of an
+// instance used to expand role collection patterns.
+type collectionsCacheEntry struct {
+	names   []string
+	expires time.Time
 }
 
+const defaultCollectionCacheTTL = time.Minute
+
 func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
-	b := backend()
-	if err := b.Setup(ctx, conf); err != nil {
-		return nil, err
+	return FactoryWithRegistry(nil)(ctx, conf)
+}
+
+// FactoryWithRegistry returns a factory for backends storing role
+// names through registry. A nil registry uses the gRPC client.
+func FactoryWithRegistry(registry RoleRegistry) logical.Factory {
+	return func(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+		b := backend(registry)
+		if err := b.Setup(ctx, conf); err != nil {
+			return nil, err
+		}
+		return b, nil
 	}
-	return b, nil
 }
 
 // backend defines the target API backend
 // for Vault. It must include each path
 // and the secrets it will store.
-func backend() *QdrantBackend {
-	var b = QdrantBackend{}
+func backend(registry RoleRegistry) *QdrantBackend {
+	var b = QdrantBackend{
+		client:       &QdrantClient{},
+		driftChecked: map[string]time.Time{},
+
+		rateLimitLocks: locksutil.CreateLocks(),
+	}
+
+	if registry == nil {
+		registry = b.client
+	}
+	b.registry = instrumentedRegistry{registry}
 
 	b.Backend = &framework.Backend{
 		Help: strings.TrimSpace(backendHelp),
@@ -40,18 +84,33 @@ func backend() *QdrantBackend {
 			SealWrapStorage: []string{
 				"config",
 				"role/*",
+				"template/*",
+				"history/*",
+				"static-creds/*",
 			},
 		},
 		Paths: framework.PathAppend(
 			pathConfig(&b),
+			pathBootstrap(&b),
 			pathRole(&b),
+			pathRoleHistory(&b),
+			pathTemplate(&b),
+			pathExport(&b),
+			pathClone(&b),
+			pathCollections(&b),
+			pathProvision(&b),
+			pathSnapshot(&b),
+			pathStaticCreds(&b),
+			pathIssued(&b),
+			pathStatus(&b),
 			pathJWT(&b),
 		),
 		Secrets: []*framework.Secret{
 			// b.hashiCupsToken(),
 		},
-		BackendType: logical.TypeLogical,
-		Invalidate:  b.invalidate,
+		BackendType:  logical.TypeLogical,
+		Invalidate:   b.invalidate,
+		PeriodicFunc: b.periodicFunc,
 	}
 	return &b
 }
@@ -75,6 +134,61 @@ func (b *QdrantBackend) invalidate(ctx context.Context, key string) {
 	if key == "config" {
 		b.reset()
 	}
+	if strings.HasPrefix(key, configPrefix) {
+		b.resetCollections(strings.TrimPrefix(key, configPrefix))
+	}
+}
+
+// resetCollections drops the cached collection names of an instance
+func (b *QdrantBackend) resetCollections(dbId string) {
+	b.collectionsMutex.Lock()
+	defer b.collectionsMutex.Unlock()
+	delete(b.collections, dbId)
+	b.collectionsEpoch++
+}
+
+// listCollections returns the collection names of an instance,
+// served from cache while the entry is fresh. The lock is not held
+// while listing, so a slow instance does not block the others; names
+// listed across a reset are not cached.
+func (b *QdrantBackend) listCollections(ctx context.Context, s logical.Storage, config *ConfigParameters) ([]string, error) {
+
+	ttl := defaultCollectionCacheTTL
+	if config.CollectionCacheTTL != "" {
+		d, err := time.ParseDuration(config.CollectionCacheTTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid collection_cache_ttl: %w", err)
+		}
+		ttl = d
+	}
+
+	b.collectionsMutex.Lock()
+	if entry, ok := b.collections[config.DBId]; ok && time.Now().Before(entry.expires) {
+		b.collectionsMutex.Unlock()
+		return entry.names, nil
+	}
+	epoch := b.collectionsEpoch
+	b.collectionsMutex.Unlock()
+
+	names, err := b.client.listCollections(ctx, s, config.DBId)
+	if err != nil {
+		return nil, err
+	}
+
+	b.collectionsMutex.Lock()
+	defer b.collectionsMutex.Unlock()
+
+	if ttl > 0 && epoch == b.collectionsEpoch {
+		if b.collections == nil {
+			b.collections = map[string]*collectionsCacheEntry{}
+		}
+		b.collections[config.DBId] = &collectionsCacheEntry{
+			names:   names,
+			expires: time.Now().Add(ttl),
+		}
+	}
+
+	return names, nil
 }
 
 func getFromStorage[T any](ctx context.Context, s logical.Storage, path string) (*T, error) {
@@ -120,6 +234,155 @@ func storeInStorage[T any](ctx context.Context, s logical.Storage, path string,
 	return nil
 }
 
+// listAfter returns the keys under the prefix sorted after the given
+// key. logical.Storage can not list a page at a time, so the prefix is
+// listed in full.
+func listAfter(ctx context.Context, s logical.Storage, prefix string, after string) ([]string, error) {
+
+	keys, err := s.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+	i := sort.Search(len(keys), func(i int) bool { return keys[i] > after })
+	return keys[i:], nil
+}
+
+// listPage returns up to limit keys under the prefix sorted after the
+// given key
+func listPage(ctx context.Context, s logical.Storage, prefix string, after string, limit int) ([]string, error) {
+
+	keys, err := listAfter(ctx, s, prefix, after)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys, nil
+}
+
+// stagedStorage buffers writes on top of a storage so a set of
+// changes can be validated before being committed
+type stagedStorage struct {
+	logical.Storage
+	puts    map[string]*logical.StorageEntry
+	deletes map[string]bool
+}
+
+func newStagedStorage(s logical.Storage) *stagedStorage {
+	return &stagedStorage{
+		Storage: s,
+		puts:    map[string]*logical.StorageEntry{},
+		deletes: map[string]bool{},
+	}
+}
+
+func (s *stagedStorage) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
+	if s.deletes[key] {
+		return nil, nil
+	}
+	if entry, ok := s.puts[key]; ok {
+		return entry, nil
+	}
+	return s.Storage.Get(ctx, key)
+}
+
+func (s *stagedStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	s.puts[entry.Key] = entry
+	delete(s.deletes, entry.Key)
+	return nil
+}
+
+func (s *stagedStorage) Delete(ctx context.Context, key string) error {
+	s.deletes[key] = true
+	delete(s.puts, key)
+	return nil
+}
+
+func (s *stagedStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	l, err := s.Storage.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]bool{}
+	for _, v := range l {
+		if !s.deletes[prefix+v] {
+			keys[v] = true
+		}
+	}
+
+	for key := range s.puts {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(key, prefix)
+		if i := strings.Index(rest, "/"); i >= 0 {
+			rest = rest[:i+1]
+		}
+		keys[rest] = true
+	}
+
+	var out []string
+	for v := range keys {
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// commit writes the staged changes to the underlying storage. If a
+// write fails the changes already written are reverted.
+func (s *stagedStorage) commit(ctx context.Context) error {
+
+	var keys []string
+	for key := range s.puts {
+		keys = append(keys, key)
+	}
+	for key := range s.deletes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	previous := map[string]*logical.StorageEntry{}
+	var done []string
+
+	rollback := func() {
+		for _, key := range done {
+			if entry := previous[key]; entry != nil {
+				s.Storage.Put(ctx, entry)
+			} else {
+				s.Storage.Delete(ctx, key)
+			}
+		}
+	}
+
+	for _, key := range keys {
+		entry, err := s.Storage.Get(ctx, key)
+		if err != nil {
+			rollback()
+			return err
+		}
+		previous[key] = entry
+
+		if put, ok := s.puts[key]; ok {
+			err = s.Storage.Put(ctx, put)
+		} else {
+			err = s.Storage.Delete(ctx, key)
+		}
+		if err != nil {
+			rollback()
+			return err
+		}
+		done = append(done, key)
+	}
+
+	return nil
+}
+
 func readOperation[T any](ctx context.Context, s logical.Storage, path string) (*logical.Response, error) {
 	t, err := getFromStorage[T](ctx, s, path)
 	if err != nil {
@@ -141,7 +404,29 @@ func readOperation[T any](ctx context.Context, s logical.Storage, path string) (
 	}, nil
 }
 
+// periodicFunc rotates the due static role credentials, checks the
+// registries for drift and prunes the issuance logs
 func (b *QdrantBackend) periodicFunc(ctx context.Context, sys *logical.Request) error {
 	b.Logger().Debug("Periodic: starting periodic func")
-	return nil
+
+	// a failing step does not stop the others
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"rotate static credentials", func() error { return b.rotateStaticCredentials(ctx, sys.Storage) }},
+		{"check drift", func() error { return b.checkDrift(ctx, sys.Storage) }},
+		{"prune issuance log", func() error { return b.pruneIssuance(ctx, sys.Storage, time.Now()) }},
+	}
+
+	var errs []error
+	for _, step := range steps {
+		err := step.run()
+		if err != nil {
+			b.Logger().Error("Periodic: step failed", "step", step.name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", step.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
 }
@@ -2,12 +2,21 @@ package qdrant
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/migrx-io/vault-plugin-secrets-qdrant/plugin/qdranttest"
+	"github.com/stretchr/testify/assert"
 )
 
+// testAPIKey is the sig_key of the test configs, which the plugin also
+// sends as the Qdrant API key
+const testAPIKey = "your-very-long-256-bit-secret-key"
+
 func getTestBackend(tb testing.TB) (*QdrantBackend, logical.Storage) {
 	tb.Helper()
 
@@ -24,3 +33,122 @@ func getTestBackend(tb testing.TB) (*QdrantBackend, logical.Storage) {
 
 	return b.(*QdrantBackend), config.StorageView
 }
+
+// getTestBackendWithServer returns a backend connected to an in-memory
+// Qdrant server
+func getTestBackendWithServer(tb testing.TB) (*QdrantBackend, logical.Storage, *qdranttest.Server) {
+	tb.Helper()
+
+	b, storage := getTestBackend(tb)
+
+	srv := qdranttest.NewServer(testAPIKey)
+	tb.Cleanup(srv.Close)
+
+	client := &QdrantClient{dialer: srv.Dialer()}
+	b.client = client
+	b.registry = instrumentedRegistry{client}
+
+	return b, storage, srv
+}
+
+// assertErrorCode checks a request failed with the error code and
+// HTTP status
+func assertErrorCode(tb testing.TB, err error, code string, status int) {
+	tb.Helper()
+
+	var e *Error
+	if assert.ErrorAs(tb, err, &e) {
+		assert.Equal(tb, code, e.ErrorCode)
+		assert.Equal(tb, status, e.Code())
+	}
+}
+
+// failingStorage fails the reads of keys under a prefix
+type failingStorage struct {
+	logical.Storage
+	prefix string
+}
+
+func (s *failingStorage) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
+	if strings.HasPrefix(key, s.prefix) {
+		return nil, errors.New("storage unavailable")
+	}
+	return s.Storage.Get(ctx, key)
+}
+
+func TestPeriodicFunc(t *testing.T) {
+
+	b, reqStorage := getTestBackend(t)
+	ctx := context.Background()
+
+	writeTestConfig(t, b, reqStorage, nil)
+
+	err := storeInStorage(ctx, reqStorage, "role/instance1/static", &RoleParameters{
+		DBId:           "instance1",
+		RoleId:         "static",
+		Kind:           roleKindStatic,
+		RotationPeriod: "1h",
+	})
+	assert.NoError(t, err)
+
+	// a failing step does not stop the others
+	err = b.periodicFunc(ctx, &logical.Request{Storage: &failingStorage{Storage: reqStorage, prefix: staticCredsPrefix}})
+	assert.ErrorContains(t, err, "rotate static credentials")
+	assert.ErrorContains(t, err, "storage unavailable")
+
+	assert.False(t, b.driftChecked["instance1"].IsZero())
+	assert.False(t, b.issuancePruned.IsZero())
+}
+
+func TestListCollections(t *testing.T) {
+
+	b, reqStorage, srv := getTestBackendWithServer(t)
+	ctx := context.Background()
+
+	srv.AddCollection("events")
+
+	slow := &ConfigParameters{DBId: "slow", URL: "localhost:6334", SignKey: testAPIKey}
+	fast := &ConfigParameters{DBId: "fast", URL: "localhost:6334", SignKey: testAPIKey}
+	for _, config := range []*ConfigParameters{slow, fast} {
+		assert.NoError(t, storeInStorage(ctx, reqStorage, configPrefix+config.DBId, config))
+	}
+
+	// names are cached per instance
+	names, err := b.listCollections(ctx, reqStorage, fast)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"events"}, names)
+
+	srv.AddCollection("users")
+	names, err = b.listCollections(ctx, reqStorage, fast)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"events"}, names)
+
+	// a slow instance does not block the cached names of the others
+	srv.Delay(qdranttest.MethodCollectionsList, time.Second)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.listCollections(ctx, reqStorage, slow)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return srv.Calls(qdranttest.MethodCollectionsList) == 2
+	}, time.Second, time.Millisecond)
+
+	start := time.Now()
+	names, err = b.listCollections(ctx, reqStorage, fast)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"events"}, names)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	// names listed across a reset are not cached
+	b.resetCollections("slow")
+	<-done
+
+	srv.Reset()
+	names, err = b.listCollections(ctx, reqStorage, slow)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"events", "users"}, names)
+	assert.Equal(t, 1, srv.Calls(qdranttest.MethodCollectionsList))
+}
@@ -0,0 +1,94 @@
+package qdrant
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/helper/cidrutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// reservedClaims are set by the plugin or checked by Qdrant and can not
+// carry the bound CIDRs
+var reservedClaims = []string{accessClaim, "value_exists", "exp", "iss", "jti"}
+
+func validateBindings(role *RoleParameters) error {
+
+	if len(role.BoundCIDRs) > 0 {
+		valid, err := cidrutil.ValidateCIDRListSlice(role.BoundCIDRs)
+		if err != nil || !valid {
+			return fmt.Errorf("invalid bound_cidrs %v", role.BoundCIDRs)
+		}
+	}
+
+	if role.BoundCIDRsClaim != "" {
+		if len(role.BoundCIDRs) == 0 {
+			return errors.New("bound_cidrs_claim requires bound_cidrs")
+		}
+		if strutil.StrListContains(reservedClaims, role.BoundCIDRsClaim) {
+			return fmt.Errorf("bound_cidrs_claim can not be the reserved claim %q", role.BoundCIDRsClaim)
+		}
+	}
+
+	return nil
+}
+
+// checkBindings fails with a permission_denied error unless the
+// requester matches every binding of the role
+func (b *QdrantBackend) checkBindings(req *logical.Request, role *RoleParameters) error {
+
+	if len(role.BoundCIDRs) > 0 {
+		remoteAddr := ""
+		if req.Connection != nil {
+			remoteAddr = req.Connection.RemoteAddr
+		}
+
+		if remoteAddr == "" {
+			return permissionDenied(fmt.Sprintf("role %q is bound to CIDRs and the request has no remote address", role.RoleId))
+		}
+
+		ok, err := cidrutil.IPBelongsToCIDRBlocksSlice(remoteAddr, role.BoundCIDRs)
+		if err != nil || !ok {
+			return permissionDenied(fmt.Sprintf("remote address %s is not in the bound CIDRs of role %q", remoteAddr, role.RoleId))
+		}
+	}
+
+	if len(role.BoundEntityIDs) > 0 && !strutil.StrListContains(role.BoundEntityIDs, req.EntityID) {
+		return permissionDenied(fmt.Sprintf("entity %q is not bound to role %q", req.EntityID, role.RoleId))
+	}
+
+	if len(role.BoundGroupIDs) > 0 {
+		member, err := b.inBoundGroup(req.EntityID, role.BoundGroupIDs)
+		if err != nil {
+			return err
+		}
+		if !member {
+			return permissionDenied(fmt.Sprintf("entity %q is not in a bound group of role %q", req.EntityID, role.RoleId))
+		}
+	}
+
+	return nil
+}
+
+// inBoundGroup reports whether the entity is a member of one of the
+// groups
+func (b *QdrantBackend) inBoundGroup(entityID string, groupIDs []string) (bool, error) {
+
+	if entityID == "" {
+		return false, nil
+	}
+
+	groups, err := b.System().GroupsForEntity(entityID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, group := range groups {
+		if group != nil && strutil.StrListContains(groupIDs, group.ID) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
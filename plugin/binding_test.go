@@ -0,0 +1,126 @@
+package qdrant
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleBindings(t *testing.T) {
+
+	b, reqStorage, _ := getTestBackendWithServer(t)
+	ctx := context.Background()
+
+	writeTestConfig(t, b, reqStorage, nil)
+
+	writeRole := func(name string, data map[string]interface{}) error {
+		data["claims"] = map[string]interface{}{"access": "r"}
+		_, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "role/instance1/" + name,
+			Storage:   reqStorage,
+			Data:      data,
+		})
+		return err
+	}
+
+	issue := func(name string, remoteAddr string, entityID string) (*logical.Response, error) {
+		req := &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "jwt/instance1/" + name,
+			Storage:   reqStorage,
+			EntityID:  entityID,
+		}
+		if remoteAddr != "" {
+			req.Connection = &logical.Connection{RemoteAddr: remoteAddr}
+		}
+		return b.HandleRequest(ctx, req)
+	}
+
+	// CIDRs
+	assert.NoError(t, writeRole("ci", map[string]interface{}{
+		"bound_cidrs":       "10.0.0.0/8,192.168.1.10/32",
+		"bound_cidrs_claim": "allowed_cidrs",
+	}))
+
+	resp, err := issue("ci", "10.1.2.3", "")
+	assert.NoError(t, err)
+
+	token, err := jwt.ParseSigned(resp.Data["token"].(string), []jose.SignatureAlgorithm{jose.HS256})
+	assert.NoError(t, err)
+
+	claims := map[string]interface{}{}
+	assert.NoError(t, token.UnsafeClaimsWithoutVerification(&claims))
+	assert.Equal(t, []interface{}{"10.0.0.0/8", "192.168.1.10/32"}, claims["allowed_cidrs"])
+
+	_, err = issue("ci", "192.168.1.10", "")
+	assert.NoError(t, err)
+
+	_, err = issue("ci", "192.168.1.11", "")
+	assertErrorCode(t, err, ErrCodePermissionDenied, http.StatusForbidden)
+
+	_, err = issue("ci", "", "")
+	assertErrorCode(t, err, ErrCodePermissionDenied, http.StatusForbidden)
+
+	// entities
+	assert.NoError(t, writeRole("deploy", map[string]interface{}{"bound_entity_ids": []string{"entity-1"}}))
+
+	resp, err = issue("deploy", "", "entity-1")
+	assert.NoError(t, err)
+
+	token, err = jwt.ParseSigned(resp.Data["token"].(string), []jose.SignatureAlgorithm{jose.HS256})
+	assert.NoError(t, err)
+
+	claims = map[string]interface{}{}
+	assert.NoError(t, token.UnsafeClaimsWithoutVerification(&claims))
+	assert.NotContains(t, claims, "allowed_cidrs")
+
+	_, err = issue("deploy", "", "entity-2")
+	assertErrorCode(t, err, ErrCodePermissionDenied, http.StatusForbidden)
+
+	// groups
+	b.System().(*logical.StaticSystemView).GroupsVal = []*logical.Group{{ID: "group-ops", Name: "ops"}}
+
+	assert.NoError(t, writeRole("ops", map[string]interface{}{"bound_group_ids": []string{"group-ops"}}))
+
+	_, err = issue("ops", "", "entity-1")
+	assert.NoError(t, err)
+
+	_, err = issue("ops", "", "")
+	assertErrorCode(t, err, ErrCodePermissionDenied, http.StatusForbidden)
+
+	b.System().(*logical.StaticSystemView).GroupsVal = []*logical.Group{{ID: "group-dev", Name: "dev"}}
+
+	_, err = issue("ops", "", "entity-1")
+	assertErrorCode(t, err, ErrCodePermissionDenied, http.StatusForbidden)
+
+	// static credentials are bound too
+	assert.NoError(t, writeRole("static", map[string]interface{}{
+		"kind":             "static",
+		"rotation_period":  "1h",
+		"bound_entity_ids": []string{"entity-1"},
+	}))
+
+	_, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "static-creds/instance1/static",
+		Storage:   reqStorage,
+		EntityID:  "entity-2",
+	})
+	assertErrorCode(t, err, ErrCodePermissionDenied, http.StatusForbidden)
+
+	// invalid bindings
+	for _, data := range []map[string]interface{}{
+		{"bound_cidrs": "10.0.0.0/33"},
+		{"bound_cidrs_claim": "allowed_cidrs"},
+		{"bound_cidrs": "10.0.0.0/8", "bound_cidrs_claim": "access"},
+	} {
+		err = writeRole("invalid", data)
+		assertErrorCode(t, err, ErrCodeInvalidRequest, http.StatusBadRequest)
+	}
+}
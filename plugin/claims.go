@@ -0,0 +1,577 @@
+package qdrant
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	accessClaim = "access"
+
+	accessRead      = "r"
+	accessReadWrite = "rw"
+	accessManage    = "m"
+
+	matchExact = "exact"
+	matchGlob  = "glob"
+	matchRegex = "regex"
+
+	defaultPatternLimit = 100
+
+	strictOff    = "off"
+	strictStrict = "strict"
+	strictWarn   = "warn"
+)
+
+// CollectionAccess is a typed access entry for a single collection.
+// Entries are merged into the signed 'access' claim. If Match is
+// 'glob' or 'regex' the Name is a pattern which is expanded against
+// the collections of the instance when a token is generated.
+type CollectionAccess struct {
+	Name    string                 `json:"name"`
+	Access  string                 `json:"access"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+	Match   string                 `json:"match,omitempty"`
+}
+
+func (c *CollectionAccess) isPattern() bool {
+	return c.Match == matchGlob || c.Match == matchRegex
+}
+
+// matches reports whether the collection name is matched by the entry
+func (c *CollectionAccess) matches(name string) (bool, error) {
+	switch c.Match {
+	case matchGlob:
+		return path.Match(c.Name, name)
+	case matchRegex:
+		re, err := regexp.Compile("^(?:" + c.Name + ")$")
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(name), nil
+	default:
+		return c.Name == name, nil
+	}
+}
+
+// AccessPolicy is the access ceiling an instance allows roles to grant
+type AccessPolicy struct {
+	// GlobalLevels lists the allowed global access levels ('r', 'm')
+	GlobalLevels []string `json:"global_levels,omitempty"`
+	// Collections lists the allowed collection names or glob patterns,
+	// any collection is allowed if empty
+	Collections []string `json:"collections,omitempty"`
+	// AllowManage must be set to grant global manage access
+	AllowManage bool `json:"allow_manage,omitempty"`
+}
+
+func validateAccessPolicy(policy *AccessPolicy) error {
+
+	if policy == nil {
+		return nil
+	}
+
+	for _, level := range policy.GlobalLevels {
+		if level != accessRead && level != accessManage {
+			return fmt.Errorf("max_access: invalid global level %q (expected %q or %q)", level, accessRead, accessManage)
+		}
+	}
+
+	for _, pattern := range policy.Collections {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("max_access: invalid collection pattern %q: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// enforceAccessPolicy checks the access claim does not exceed the policy
+func enforceAccessPolicy(policy *AccessPolicy, claims map[string]interface{}) error {
+
+	if policy == nil {
+		return nil
+	}
+
+	access := claims[accessClaim]
+	if access == nil {
+		// Qdrant grants manage access to tokens without an access claim
+		access = accessManage
+	}
+
+	switch access := access.(type) {
+	case string:
+		if access == accessManage && !policy.AllowManage {
+			return fmt.Errorf("global access %q exceeds instance max_access: manage is not allowed", access)
+		}
+		for _, level := range policy.GlobalLevels {
+			if level == access {
+				return nil
+			}
+		}
+		return fmt.Errorf("global access %q exceeds instance max_access", access)
+	case []interface{}:
+		for _, v := range access {
+			entry, ok := v.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("unsupported access entry type %T", v)
+			}
+
+			name, _ := entry["collection"].(string)
+			if !policy.allowsCollection(name) {
+				return fmt.Errorf("access to collection %q exceeds instance max_access", name)
+			}
+
+			level, _ := entry["access"].(string)
+			if level != accessRead && level != accessReadWrite {
+				return fmt.Errorf("access %q to collection %q exceeds instance max_access", level, name)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported access claim type %T", access)
+	}
+}
+
+func (p *AccessPolicy) allowsCollection(name string) bool {
+
+	if len(p.Collections) == 0 {
+		return true
+	}
+
+	for _, pattern := range p.Collections {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// exactCollections returns the entries which are not patterns
+func exactCollections(collections []CollectionAccess) []CollectionAccess {
+
+	var exact []CollectionAccess
+	for _, c := range collections {
+		if !c.isPattern() {
+			exact = append(exact, c)
+		}
+	}
+	return exact
+}
+
+func validateCollections(collections []CollectionAccess) error {
+
+	seen := map[string]bool{}
+
+	for i, c := range collections {
+
+		if c.Name == "" {
+			return fmt.Errorf("collections[%d]: missing name", i)
+		}
+
+		if seen[c.Name] {
+			return fmt.Errorf("collections[%d]: duplicate collection %q", i, c.Name)
+		}
+		seen[c.Name] = true
+
+		switch c.Match {
+		case "", matchExact, matchGlob, matchRegex:
+		default:
+			return fmt.Errorf("collections[%d]: invalid match %q for %q (expected %q, %q or %q)", i, c.Match, c.Name, matchExact, matchGlob, matchRegex)
+		}
+
+		if _, err := c.matches(""); err != nil {
+			return fmt.Errorf("collections[%d]: invalid pattern %q: %w", i, c.Name, err)
+		}
+
+		if c.Access != accessRead && c.Access != accessReadWrite {
+			return fmt.Errorf("collections[%d]: invalid access %q for %q (expected %q or %q)", i, c.Access, c.Name, accessRead, accessReadWrite)
+		}
+
+		for k, v := range c.Payload {
+			switch v.(type) {
+			case string, bool, float64, int, int64:
+			default:
+				return fmt.Errorf("collections[%d]: payload key %q of %q must be a string, number or bool", i, k, c.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// buildClaims returns a copy of role claims with typed collections
+// merged into the 'access' claim.
+func buildClaims(role *RoleParameters) (map[string]interface{}, error) {
+
+	claims := map[string]interface{}{}
+	for k, v := range role.Claims {
+		claims[k] = v
+	}
+
+	if role.Collections == nil {
+		return claims, nil
+	}
+
+	access, err := mergeAccess(claims[accessClaim], role.Collections)
+	if err != nil {
+		return nil, err
+	}
+
+	claims[accessClaim] = access
+
+	return claims, nil
+}
+
+func mergeAccess(current interface{}, collections []CollectionAccess) ([]interface{}, error) {
+
+	access := []interface{}{}
+
+	switch v := current.(type) {
+	case nil:
+	case []interface{}:
+		access = append(access, v...)
+	case string:
+		return nil, fmt.Errorf("global access %q can not be combined with collections", v)
+	default:
+		return nil, fmt.Errorf("unsupported access claim type %T", current)
+	}
+
+	for _, c := range collections {
+		entry := map[string]interface{}{
+			"collection": c.Name,
+			"access":     c.Access,
+		}
+		if len(c.Payload) > 0 {
+			entry["payload"] = c.Payload
+		}
+		access = append(access, entry)
+	}
+
+	return access, nil
+}
+
+func hasPatterns(collections []CollectionAccess) bool {
+	for _, c := range collections {
+		if c.isPattern() {
+			return true
+		}
+	}
+	return false
+}
+
+// expandCollections replaces pattern entries with one entry per matching
+// collection name. Exact entries take precedence over patterns, and the
+// first matching pattern wins. An error is returned if patterns expand to
+// more than limit collections.
+func expandCollections(collections []CollectionAccess, names []string, limit int) ([]CollectionAccess, error) {
+
+	var expanded []CollectionAccess
+
+	seen := map[string]bool{}
+	for _, c := range collections {
+		if !c.isPattern() {
+			expanded = append(expanded, c)
+			seen[c.Name] = true
+		}
+	}
+
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+
+	count := 0
+	for _, c := range collections {
+		if !c.isPattern() {
+			continue
+		}
+
+		for _, name := range sorted {
+			if seen[name] {
+				continue
+			}
+
+			ok, err := c.matches(name)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+
+			count++
+			if count > limit {
+				return nil, fmt.Errorf("collection patterns match more than %d collections", limit)
+			}
+
+			seen[name] = true
+			expanded = append(expanded, CollectionAccess{
+				Name:    name,
+				Access:  c.Access,
+				Payload: c.Payload,
+			})
+		}
+	}
+
+	return expanded, nil
+}
+
+func validateStrictCollections(mode string) error {
+	switch mode {
+	case "", strictOff, strictStrict, strictWarn:
+		return nil
+	}
+	return fmt.Errorf("invalid strict_collections %q (expected %q, %q or %q)", mode, strictOff, strictStrict, strictWarn)
+}
+
+// strictCollectionsMode returns the role mode, falling back to the instance mode
+func strictCollectionsMode(config *ConfigParameters, role *RoleParameters) string {
+	if role.StrictCollections != "" {
+		return role.StrictCollections
+	}
+	if config.StrictCollections != "" {
+		return config.StrictCollections
+	}
+	return strictOff
+}
+
+// referencedCollections returns the sorted names of collections a role
+// refers to by exact name in its collections and claims
+func referencedCollections(role *RoleParameters) []string {
+
+	seen := map[string]bool{}
+
+	for _, c := range role.Collections {
+		if !c.isPattern() {
+			seen[c.Name] = true
+		}
+	}
+
+	if access, ok := role.Claims[accessClaim].([]interface{}); ok {
+		for _, v := range access {
+			if entry, ok := v.(map[string]interface{}); ok {
+				if name, ok := entry["collection"].(string); ok && name != "" {
+					seen[name] = true
+				}
+			}
+		}
+	}
+
+	if valueExists, ok := role.Claims["value_exists"].(map[string]interface{}); ok {
+		if name, ok := valueExists["collection"].(string); ok && name != "" {
+			seen[name] = true
+		}
+	}
+
+	var names []string
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func missingCollectionsMessage(missing []string) string {
+	return "missing collections: " + strings.Join(missing, ", ")
+}
+
+// mergeClaims returns base overlaid with the overlay claims. Access
+// lists are concatenated, with overlay entries replacing base entries
+// for the same collection.
+func mergeClaims(base map[string]interface{}, overlay map[string]interface{}) (map[string]interface{}, error) {
+
+	out := map[string]interface{}{}
+	for k, v := range base {
+		out[k] = v
+	}
+
+	for k, v := range overlay {
+		if k != accessClaim || out[k] == nil {
+			out[k] = v
+			continue
+		}
+
+		current, currentIsList := out[k].([]interface{})
+		next, nextIsList := v.([]interface{})
+
+		switch {
+		case currentIsList && nextIsList:
+			out[k] = mergeAccessLists(current, next)
+		case !currentIsList && !nextIsList:
+			out[k] = v
+		default:
+			return nil, fmt.Errorf("global access can not be combined with collection access")
+		}
+	}
+
+	return out, nil
+}
+
+func mergeAccessLists(current []interface{}, next []interface{}) []interface{} {
+
+	index := map[string]int{}
+	out := append([]interface{}{}, current...)
+
+	for i, v := range out {
+		if entry, ok := v.(map[string]interface{}); ok {
+			if name, ok := entry["collection"].(string); ok {
+				index[name] = i
+			}
+		}
+	}
+
+	for _, v := range next {
+		if entry, ok := v.(map[string]interface{}); ok {
+			if name, ok := entry["collection"].(string); ok {
+				if i, ok := index[name]; ok {
+					out[i] = v
+					continue
+				}
+				index[name] = len(out)
+			}
+		}
+		out = append(out, v)
+	}
+
+	return out
+}
+
+// mergeCollectionList concatenates collection entries, with next
+// entries replacing current entries of the same name
+func mergeCollectionList(current []CollectionAccess, next []CollectionAccess) []CollectionAccess {
+
+	index := map[string]int{}
+	out := append([]CollectionAccess{}, current...)
+
+	for i, c := range out {
+		index[c.Name] = i
+	}
+
+	for _, c := range next {
+		if i, ok := index[c.Name]; ok {
+			out[i] = c
+			continue
+		}
+		index[c.Name] = len(out)
+		out = append(out, c)
+	}
+
+	return out
+}
+
+// grantsAccess reports whether the claims and collection patterns of a
+// resolved role grant at least the access level on the collection. An
+// empty collection or level matches any; global access matches every
+// collection and claims without access grant global manage access.
+func grantsAccess(claims map[string]interface{}, patterns []CollectionAccess, collection string, level string) (bool, error) {
+
+	switch access := claims[accessClaim].(type) {
+	case nil:
+		return grantsLevel(accessManage, level), nil
+	case string:
+		return grantsLevel(access, level), nil
+	case []interface{}:
+		for _, v := range access {
+			entry, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			granted, _ := entry["access"].(string)
+			if (collection == "" || entry["collection"] == collection) && grantsLevel(granted, level) {
+				return true, nil
+			}
+		}
+	}
+
+	for _, c := range patterns {
+		if !grantsLevel(c.Access, level) {
+			continue
+		}
+		if collection == "" {
+			return true, nil
+		}
+		ok, err := c.matches(collection)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// grantsLevel reports whether the granted access level includes the
+// level, levels rank r < rw < m
+func grantsLevel(granted string, level string) bool {
+	return level == "" || accessRank(granted) >= accessRank(level)
+}
+
+func accessRank(level string) int {
+	switch level {
+	case accessRead:
+		return 1
+	case accessReadWrite:
+		return 2
+	case accessManage:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// CollectionGrant is the effective access a role has on a collection
+// and the claim it comes from: 'global', 'exact' or the matching
+// pattern.
+type CollectionGrant struct {
+	Access  string                 `json:"access"`
+	Source  string                 `json:"source"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+}
+
+// effectiveAccess returns the access the claims and collection patterns
+// of a resolved role grant on the collection, or nil. Exact entries
+// take precedence over patterns. Claims without access grant global
+// manage access.
+func effectiveAccess(claims map[string]interface{}, patterns []CollectionAccess, collection string) (*CollectionGrant, error) {
+
+	switch access := claims[accessClaim].(type) {
+	case nil:
+		return &CollectionGrant{Access: accessManage, Source: "global"}, nil
+	case string:
+		if access != "" {
+			return &CollectionGrant{Access: access, Source: "global"}, nil
+		}
+	case []interface{}:
+		for _, v := range access {
+			entry, ok := v.(map[string]interface{})
+			if !ok || entry["collection"] != collection {
+				continue
+			}
+			grant := &CollectionGrant{Source: matchExact}
+			grant.Access, _ = entry["access"].(string)
+			grant.Payload, _ = entry["payload"].(map[string]interface{})
+			return grant, nil
+		}
+	}
+
+	for _, c := range patterns {
+		ok, err := c.matches(collection)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return &CollectionGrant{
+				Access:  c.Access,
+				Source:  c.Match + ":" + c.Name,
+				Payload: c.Payload,
+			}, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,207 @@
+package qdrant
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCollections(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NoError(validateCollections([]CollectionAccess{
+		{Name: "users", Access: "r"},
+		{Name: "orders", Access: "rw", Payload: map[string]interface{}{"tenant": "acme"}},
+	}))
+
+	assert.Error(validateCollections([]CollectionAccess{{Name: "", Access: "r"}}))
+	assert.Error(validateCollections([]CollectionAccess{{Name: "users", Access: "m"}}))
+	assert.Error(validateCollections([]CollectionAccess{
+		{Name: "users", Access: "r"},
+		{Name: "users", Access: "rw"},
+	}))
+	assert.Error(validateCollections([]CollectionAccess{
+		{Name: "users", Access: "r", Payload: map[string]interface{}{"tenant": []interface{}{"a"}}},
+	}))
+}
+
+func TestBuildClaims(t *testing.T) {
+	assert := assert.New(t)
+
+	role := &RoleParameters{
+		Claims: map[string]interface{}{
+			"access": []interface{}{
+				map[string]interface{}{"collection": "users", "access": "r"},
+			},
+		},
+		Collections: []CollectionAccess{
+			{Name: "orders", Access: "rw", Payload: map[string]interface{}{"tenant": "acme"}},
+		},
+	}
+
+	claims, err := buildClaims(role)
+	assert.NoError(err)
+	assert.Equal([]interface{}{
+		map[string]interface{}{"collection": "users", "access": "r"},
+		map[string]interface{}{"collection": "orders", "access": "rw", "payload": map[string]interface{}{"tenant": "acme"}},
+	}, claims["access"])
+
+	// stored claims are left untouched
+	assert.Len(role.Claims["access"], 1)
+
+	// collections only, no claims
+	claims, err = buildClaims(&RoleParameters{
+		Collections: []CollectionAccess{{Name: "orders", Access: "r"}},
+	})
+	assert.NoError(err)
+	assert.Equal([]interface{}{
+		map[string]interface{}{"collection": "orders", "access": "r"},
+	}, claims["access"])
+
+	// global access can not be combined with collections
+	_, err = buildClaims(&RoleParameters{
+		Claims:      map[string]interface{}{"access": "m"},
+		Collections: []CollectionAccess{{Name: "orders", Access: "r"}},
+	})
+	assert.Error(err)
+}
+
+func TestExpandCollections(t *testing.T) {
+	assert := assert.New(t)
+
+	names := []string{"events_acme_2026_10", "events_acme_2026_09", "events_globex_2026_10", "users"}
+
+	expanded, err := expandCollections([]CollectionAccess{
+		{Name: "users", Access: "rw"},
+		{Name: "events_acme_*", Access: "r", Match: "glob"},
+		{Name: "events_.*_2026_10", Access: "rw", Match: "regex"},
+	}, names, 10)
+	assert.NoError(err)
+	assert.Equal([]CollectionAccess{
+		{Name: "users", Access: "rw"},
+		{Name: "events_acme_2026_09", Access: "r"},
+		{Name: "events_acme_2026_10", Access: "r"},
+		{Name: "events_globex_2026_10", Access: "rw"},
+	}, expanded)
+
+	// patterns matching nothing expand to nothing
+	expanded, err = expandCollections([]CollectionAccess{
+		{Name: "logs_*", Access: "r", Match: "glob"},
+	}, names, 10)
+	assert.NoError(err)
+	assert.Empty(expanded)
+
+	// cap on expanded collections
+	_, err = expandCollections([]CollectionAccess{
+		{Name: "events_*", Access: "r", Match: "glob"},
+	}, names, 2)
+	assert.Error(err)
+
+	// invalid patterns are rejected on validation
+	assert.Error(validateCollections([]CollectionAccess{{Name: "events_[", Access: "r", Match: "glob"}}))
+	assert.Error(validateCollections([]CollectionAccess{{Name: "events_(", Access: "r", Match: "regex"}}))
+	assert.Error(validateCollections([]CollectionAccess{{Name: "events", Access: "r", Match: "prefix"}}))
+}
+
+func TestReferencedCollections(t *testing.T) {
+	assert := assert.New(t)
+
+	role := &RoleParameters{
+		Claims: map[string]interface{}{
+			"value_exists": map[string]interface{}{
+				"collection": "sys_roles",
+			},
+			"access": []interface{}{
+				map[string]interface{}{"collection": "users", "access": "r"},
+			},
+		},
+		Collections: []CollectionAccess{
+			{Name: "orders", Access: "rw"},
+			{Name: "users", Access: "r"},
+			{Name: "events_*", Access: "r", Match: "glob"},
+		},
+	}
+
+	assert.Equal([]string{"orders", "sys_roles", "users"}, referencedCollections(role))
+	assert.Empty(referencedCollections(&RoleParameters{Claims: map[string]interface{}{"access": "r"}}))
+}
+
+func TestStrictCollectionsMode(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("off", strictCollectionsMode(&ConfigParameters{}, &RoleParameters{}))
+	assert.Equal("warn", strictCollectionsMode(&ConfigParameters{StrictCollections: "warn"}, &RoleParameters{}))
+	assert.Equal("strict", strictCollectionsMode(&ConfigParameters{StrictCollections: "warn"}, &RoleParameters{StrictCollections: "strict"}))
+
+	assert.NoError(validateStrictCollections(""))
+	assert.Error(validateStrictCollections("yes"))
+}
+
+func TestMergeClaims(t *testing.T) {
+	assert := assert.New(t)
+
+	merged, err := mergeClaims(map[string]interface{}{
+		"value_exists": "a",
+		"access": []interface{}{
+			map[string]interface{}{"collection": "users", "access": "r"},
+		},
+	}, map[string]interface{}{
+		"value_exists": "b",
+		"access": []interface{}{
+			map[string]interface{}{"collection": "users", "access": "rw"},
+			map[string]interface{}{"collection": "orders", "access": "r"},
+		},
+	})
+	assert.NoError(err)
+	assert.Equal(map[string]interface{}{
+		"value_exists": "b",
+		"access": []interface{}{
+			map[string]interface{}{"collection": "users", "access": "rw"},
+			map[string]interface{}{"collection": "orders", "access": "r"},
+		},
+	}, merged)
+
+	_, err = mergeClaims(map[string]interface{}{"access": "r"}, map[string]interface{}{
+		"access": []interface{}{map[string]interface{}{"collection": "users", "access": "r"}},
+	})
+	assert.Error(err)
+}
+
+func TestEnforceAccessPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	policy := &AccessPolicy{
+		GlobalLevels: []string{"r", "m"},
+		Collections:  []string{"events_*", "users"},
+	}
+	assert.NoError(validateAccessPolicy(policy))
+	assert.Error(validateAccessPolicy(&AccessPolicy{GlobalLevels: []string{"rw"}}))
+
+	assert.NoError(enforceAccessPolicy(nil, map[string]interface{}{"access": "m"}))
+	assert.NoError(enforceAccessPolicy(policy, map[string]interface{}{"access": "r"}))
+
+	// manage requires allow_manage
+	assert.Error(enforceAccessPolicy(policy, map[string]interface{}{"access": "m"}))
+	policy.AllowManage = true
+	assert.NoError(enforceAccessPolicy(policy, map[string]interface{}{"access": "m"}))
+
+	// a missing access claim is global manage access
+	assert.NoError(enforceAccessPolicy(policy, map[string]interface{}{}))
+	assert.Error(enforceAccessPolicy(&AccessPolicy{GlobalLevels: []string{"m"}}, map[string]interface{}{}))
+	assert.Error(enforceAccessPolicy(&AccessPolicy{GlobalLevels: []string{"r"}, AllowManage: true}, map[string]interface{}{}))
+
+	// global level not whitelisted
+	assert.Error(enforceAccessPolicy(&AccessPolicy{}, map[string]interface{}{"access": "r"}))
+
+	assert.NoError(enforceAccessPolicy(policy, map[string]interface{}{
+		"access": []interface{}{
+			map[string]interface{}{"collection": "events_acme", "access": "rw"},
+			map[string]interface{}{"collection": "users", "access": "r"},
+		},
+	}))
+	assert.Error(enforceAccessPolicy(policy, map[string]interface{}{
+		"access": []interface{}{
+			map[string]interface{}{"collection": "orders", "access": "r"},
+		},
+	}))
+}
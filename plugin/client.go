@@ -5,6 +5,8 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"net"
+	"sort"
 	"time"
 
 	"encoding/base64"
@@ -12,25 +14,41 @@ import (
 
 	"github.com/hashicorp/vault/sdk/logical"
 
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
 
 	pb "github.com/qdrant/go-client/qdrant"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
 )
 
 const (
 	SYS_ROLE_TABLE = "sys_roles"
+
+	// registryLayout is the sys_roles layout set up by bootstrap: role
+	// points with 'role' and 'managed_by' payload fields and a keyword
+	// index on 'role'. Layout 1 points only carry 'role'.
+	registryLayout    = 2
+	registryManagedBy = "vault"
+
+	bootstrapTimeout = 30 * time.Second
 )
 
 type QdrantClient struct {
 	//client *grpc.ClientConn
+
+	// dialer replaces the network dialer, tests use it to connect to
+	// an in-memory server
+	dialer func(context.Context, string) (net.Conn, error)
 }
 
-func (c *QdrantClient) createRole(ctx context.Context, s logical.Storage, role *RoleParameters) error {
+// EnsureRegistry creates the role collection of the instance if it
+// does not exist yet
+func (c *QdrantClient) EnsureRegistry(ctx context.Context, s logical.Storage, dbId string) error {
 
-	conn, err := getClientQdrant(ctx, s, role.DBId)
+	conn, err := c.getClientQdrant(ctx, s, dbId)
 
 	if err != nil {
 		return err
@@ -39,50 +57,130 @@ func (c *QdrantClient) createRole(ctx context.Context, s logical.Storage, role *
 	defer conn.Close()
 
 	client := pb.NewCollectionsClient(conn)
-	client_p := pb.NewPointsClient(conn) //PointsClient
 
 	// Contact the server
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := rpcContext(ctx, time.Second)
 	defer cancel()
 
-	isExists, err := checkExistCollection(ctx, client)
+	return ensureRoleCollection(ctx, client)
+
+}
+
+// PutRoles replaces the role points of names with a single Delete
+// and a single Upsert call
+func (c *QdrantClient) PutRoles(ctx context.Context, s logical.Storage, dbId string, names []string) error {
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	conn, err := c.getClientQdrant(ctx, s, dbId)
 
 	if err != nil {
 		return err
 	}
 
-	if !isExists {
-		//create colection
-		err = createNewCollection(ctx, client)
+	defer conn.Close()
+
+	client := pb.NewCollectionsClient(conn)
+	client_p := pb.NewPointsClient(conn) //PointsClient
+
+	bootstrapped, err := registryBootstrapped(ctx, s, dbId)
+	if err != nil {
+		return err
+	}
+
+	// Contact the server
+	ctx, cancel := rpcContext(ctx, time.Second)
+	defer cancel()
+
+	// bootstrapped registries have their collection and index
+	if !bootstrapped {
+		err = ensureRoleCollection(ctx, client)
+		if err != nil {
+			return err
+		}
 
+		err = createRoleIndex(ctx, client_p)
 		if err != nil {
 			return err
 		}
+	}
 
+	// delete same keys if exists
+	err = deleteRolePoints(ctx, client_p, names)
+	if err != nil {
+		return bootstrapError(bootstrapped, err)
 	}
 
-	// delete same key if exists
-	err = deleteRolePoint(ctx, client_p, role.RoleId)
+	//add new role names
+	err = createRolePoints(ctx, client_p, names)
+	if err != nil {
+		return bootstrapError(bootstrapped, err)
+	}
+
+	return nil
+
+}
+
+// DeleteRoles removes the role points of names with a single Delete call
+func (c *QdrantClient) DeleteRoles(ctx context.Context, s logical.Storage, dbId string, names []string) error {
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	conn, err := c.getClientQdrant(ctx, s, dbId)
+
 	if err != nil {
 		return err
 	}
 
-	//add new role name
-	err = createRolePoint(ctx, client_p, role.RoleId)
+	defer conn.Close()
+
+	client := pb.NewCollectionsClient(conn)
+	client_p := pb.NewPointsClient(conn) //PointsClient
+
+	bootstrapped, err := registryBootstrapped(ctx, s, dbId)
 	if err != nil {
 		return err
 	}
 
+	// Contact the server
+	ctx, cancel := rpcContext(ctx, time.Second)
+	defer cancel()
+
+	isExists := bootstrapped
+	if !bootstrapped {
+		isExists, err = checkExistCollection(ctx, client)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if isExists {
+		//delete points
+		err = deleteRolePoints(ctx, client_p, names)
+
+		if err != nil {
+			return bootstrapError(bootstrapped, err)
+		}
+
+	}
+
 	return nil
 
 }
 
-func (c *QdrantClient) deleteRole(ctx context.Context, s logical.Storage, role *RoleParameters) error {
+// ListRoles returns the sorted role names stored in the role
+// collection of the instance
+func (c *QdrantClient) ListRoles(ctx context.Context, s logical.Storage, dbId string) ([]string, error) {
 
-	conn, err := getClientQdrant(ctx, s, role.DBId)
+	conn, err := c.getClientQdrant(ctx, s, dbId)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	defer conn.Close()
@@ -91,26 +189,466 @@ func (c *QdrantClient) deleteRole(ctx context.Context, s logical.Storage, role *
 	client_p := pb.NewPointsClient(conn) //PointsClient
 
 	// Contact the server
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := rpcContext(ctx, time.Second)
 	defer cancel()
 
 	isExists, err := checkExistCollection(ctx, client)
 
+	if err != nil {
+		return nil, err
+	}
+
+	if !isExists {
+		return []string{}, nil
+	}
+
+	seen := map[string]bool{}
+	names := []string{}
+
+	var offset *pb.PointId
+	limit := uint32(rolePageSize)
+
+	for {
+		resp, err := client_p.Scroll(ctx, &pb.ScrollPoints{
+			CollectionName: SYS_ROLE_TABLE,
+			Offset:         offset,
+			Limit:          &limit,
+			WithPayload: &pb.WithPayloadSelector{
+				SelectorOptions: &pb.WithPayloadSelector_Include{
+					Include: &pb.PayloadIncludeSelector{Fields: []string{"role"}},
+				},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, point := range resp.Result {
+			name := point.Payload["role"].GetStringValue()
+			if name != "" && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+
+		if resp.NextPageOffset == nil {
+			break
+		}
+		offset = resp.NextPageOffset
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+
+}
+
+// Health checks the instance answers with the configured API key
+func (c *QdrantClient) Health(ctx context.Context, s logical.Storage, dbId string) error {
+
+	conn, err := c.getClientQdrant(ctx, s, dbId)
+
 	if err != nil {
 		return err
 	}
 
-	if isExists {
-		//delete point
-		err = deleteRolePoint(ctx, client_p, role.RoleId)
+	defer conn.Close()
+
+	client := pb.NewQdrantClient(conn)
+
+	// Contact the server
+	ctx, cancel := rpcContext(ctx, time.Second)
+	defer cancel()
+
+	_, err = client.HealthCheck(ctx, &pb.HealthCheckRequest{})
+
+	return err
+
+}
+
+// RegistrySpec holds the parameters of a role collection created by
+// bootstrap
+type RegistrySpec struct {
+	OnDisk            bool
+	ShardNumber       uint32
+	ReplicationFactor uint32
+}
+
+// BootstrapResult reports the changes made by a bootstrap
+type BootstrapResult struct {
+	Created        bool     `json:"created"`
+	IndexesCreated []string `json:"indexes_created"`
+	MigratedPoints uint64   `json:"migrated_points"`
+	Layout         int      `json:"layout"`
+}
+
+// Bootstrap creates the role collection of the instance from
+// the spec if it is missing, checks its vectors, creates the missing
+// payload indexes and migrates points of earlier layouts. It can be run
+// again on a bootstrapped registry.
+func (c *QdrantClient) Bootstrap(ctx context.Context, s logical.Storage, dbId string, spec *RegistrySpec) (*BootstrapResult, error) {
+
+	conn, err := c.getClientQdrant(ctx, s, dbId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	client := pb.NewCollectionsClient(conn)
+	client_p := pb.NewPointsClient(conn)
 
+	// Contact the server
+	ctx, cancel := rpcContext(ctx, bootstrapTimeout)
+	defer cancel()
+
+	result := &BootstrapResult{
+		IndexesCreated: []string{},
+		Layout:         registryLayout,
+	}
+
+	isExists, err := checkExistCollection(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isExists {
+		create := &pb.CreateCollection{
+			CollectionName: SYS_ROLE_TABLE,
+			VectorsConfig: &pb.VectorsConfig{Config: &pb.VectorsConfig_Params{
+				Params: &pb.VectorParams{
+					Size:     1,
+					Distance: pb.Distance_Dot,
+					OnDisk:   &spec.OnDisk,
+				},
+			}},
+		}
+		if spec.ShardNumber > 0 {
+			create.ShardNumber = &spec.ShardNumber
+		}
+		if spec.ReplicationFactor > 0 {
+			create.ReplicationFactor = &spec.ReplicationFactor
+		}
+
+		_, err = client.Create(ctx, create)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		result.Created = true
+	}
+
+	// validate the layout
+	info, err := client.Get(ctx, &pb.GetCollectionInfoRequest{CollectionName: SYS_ROLE_TABLE})
+	if err != nil {
+		return nil, err
+	}
+
+	params := info.GetResult().GetConfig().GetParams().GetVectorsConfig().GetParams()
+	if params == nil || params.Size != 1 {
+		return nil, conflict(fmt.Sprintf("%s must have a single unnamed vector of size 1", SYS_ROLE_TABLE))
+	}
+
+	schema, ok := info.GetResult().GetPayloadSchema()["role"]
+	if ok && schema.DataType != pb.PayloadSchemaType_Keyword {
+		return nil, conflict(fmt.Sprintf("%s 'role' index must be a keyword index, not %s", SYS_ROLE_TABLE, schema.DataType))
+	}
+
+	if !ok {
+		err = createRoleIndex(ctx, client_p)
+		if err != nil {
+			return nil, err
 		}
+		result.IndexesCreated = append(result.IndexesCreated, "role")
+	}
 
+	// mark the points of earlier layouts as managed by Vault
+	unmanaged := &pb.Filter{
+		Must: []*pb.Condition{{
+			ConditionOneOf: &pb.Condition_IsEmpty{IsEmpty: &pb.IsEmptyCondition{Key: "managed_by"}},
+		}},
 	}
 
-	return nil
+	exact := true
+	count, err := client_p.Count(ctx, &pb.CountPoints{CollectionName: SYS_ROLE_TABLE, Filter: unmanaged, Exact: &exact})
+	if err != nil {
+		return nil, err
+	}
+
+	result.MigratedPoints = count.GetResult().GetCount()
+
+	if result.MigratedPoints > 0 {
+		wait := true
+		_, err = client_p.SetPayload(ctx, &pb.SetPayloadPoints{
+			CollectionName: SYS_ROLE_TABLE,
+			Wait:           &wait,
+			Payload: map[string]*pb.Value{
+				"managed_by": {Kind: &pb.Value_StringValue{StringValue: registryManagedBy}},
+			},
+			PointsSelector: &pb.PointsSelector{
+				PointsSelectorOneOf: &pb.PointsSelector_Filter{Filter: unmanaged},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+
+}
+
+// bootstrapError explains a missing role collection of a bootstrapped
+// registry, which role writes no longer create
+func bootstrapError(bootstrapped bool, err error) error {
+
+	if bootstrapped && status.Code(err) == codes.NotFound {
+		return fmt.Errorf("%s is missing, bootstrap the instance again: %w", SYS_ROLE_TABLE, err)
+	}
+
+	return err
+}
+
+// RegistryStatus is the state of an instance and its role collection
+type RegistryStatus struct {
+	Latency         time.Duration
+	Version         string
+	Exists          bool
+	RoleIndexExists bool
+	Points          uint64
+
+	// RegistryErr is why the role collection of a reachable instance
+	// could not be described
+	RegistryErr error
+}
+
+// Status checks the instance answers and describes its role
+// collection. Latency is the round-trip time of the health check. An
+// error is returned only if the instance does not answer.
+func (c *QdrantClient) Status(ctx context.Context, s logical.Storage, dbId string) (*RegistryStatus, error) {
+
+	conn, err := c.getClientQdrant(ctx, s, dbId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	client := pb.NewCollectionsClient(conn)
+	client_p := pb.NewPointsClient(conn)
+
+	// Contact the server
+	ctx, cancel := rpcContext(ctx, time.Second)
+	defer cancel()
+
+	start := time.Now()
+
+	health, err := pb.NewQdrantClient(conn).HealthCheck(ctx, &pb.HealthCheckRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	status := &RegistryStatus{
+		Latency: time.Since(start),
+		Version: health.Version,
+	}
+
+	status.Exists, err = checkExistCollection(ctx, client)
+	if err != nil {
+		status.RegistryErr = err
+		return status, nil
+	}
+
+	if !status.Exists {
+		return status, nil
+	}
+
+	info, err := client.Get(ctx, &pb.GetCollectionInfoRequest{CollectionName: SYS_ROLE_TABLE})
+	if err != nil {
+		status.RegistryErr = err
+		return status, nil
+	}
+
+	_, status.RoleIndexExists = info.GetResult().GetPayloadSchema()["role"]
+
+	exact := true
+	count, err := client_p.Count(ctx, &pb.CountPoints{CollectionName: SYS_ROLE_TABLE, Exact: &exact})
+	if err != nil {
+		status.RegistryErr = err
+		return status, nil
+	}
+
+	status.Points = count.GetResult().GetCount()
+
+	return status, nil
+
+}
+
+func (c *QdrantClient) listCollections(ctx context.Context, s logical.Storage, dbId string) ([]string, error) {
+
+	conn, err := c.getClientQdrant(ctx, s, dbId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	client := pb.NewCollectionsClient(conn)
+
+	// Contact the server
+	ctx, cancel := rpcContext(ctx, time.Second)
+	defer cancel()
+
+	resp, err := client.List(ctx, &pb.ListCollectionsRequest{})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not list collections: %w", err)
+	}
+
+	var names []string
+	for _, v := range resp.Collections {
+		names = append(names, v.Name)
+	}
+
+	return names, nil
+
+}
+
+// CollectionInfo describes a collection of an instance
+type CollectionInfo struct {
+	Name          string                 `json:"name"`
+	Status        string                 `json:"status"`
+	PointsCount   uint64                 `json:"points_count"`
+	Vectors       map[string]*VectorInfo `json:"vectors"`
+	SparseVectors []string               `json:"sparse_vectors,omitempty"`
+	Aliases       []string               `json:"aliases"`
+}
+
+// VectorInfo describes a vector of a collection. The unnamed vector
+// of a collection is keyed 'default'.
+type VectorInfo struct {
+	Size     uint64 `json:"size"`
+	Distance string `json:"distance"`
+	OnDisk   bool   `json:"on_disk,omitempty"`
+}
+
+// describeCollections returns the info and aliases of the collections
+// using a single connection
+func (c *QdrantClient) describeCollections(ctx context.Context, s logical.Storage, dbId string, names []string) ([]*CollectionInfo, error) {
+
+	conn, err := c.getClientQdrant(ctx, s, dbId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	client := pb.NewCollectionsClient(conn)
+
+	var infos []*CollectionInfo
+	for _, name := range names {
+		info, err := describeCollection(ctx, client, name)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+
+}
+
+func describeCollection(ctx context.Context, client pb.CollectionsClient, name string) (*CollectionInfo, error) {
+
+	// Contact the server
+	ctx, cancel := rpcContext(ctx, time.Second)
+	defer cancel()
+
+	resp, err := client.Get(ctx, &pb.GetCollectionInfoRequest{CollectionName: name})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not get collection %s: %w", name, err)
+	}
+
+	aliases, err := client.ListCollectionAliases(ctx, &pb.ListCollectionAliasesRequest{CollectionName: name})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not list aliases of collection %s: %w", name, err)
+	}
+
+	result := resp.GetResult()
+	params := result.GetConfig().GetParams()
+
+	info := &CollectionInfo{
+		Name:        name,
+		Status:      result.GetStatus().String(),
+		PointsCount: result.GetPointsCount(),
+		Vectors:     map[string]*VectorInfo{},
+		Aliases:     []string{},
+	}
+
+	vectors := params.GetVectorsConfig()
+	if v := vectors.GetParams(); v != nil {
+		info.Vectors["default"] = vectorInfo(v)
+	}
+	for k, v := range vectors.GetParamsMap().GetMap() {
+		info.Vectors[k] = vectorInfo(v)
+	}
+
+	for k := range params.GetSparseVectorsConfig().GetMap() {
+		info.SparseVectors = append(info.SparseVectors, k)
+	}
+	sort.Strings(info.SparseVectors)
+
+	for _, a := range aliases.GetAliases() {
+		info.Aliases = append(info.Aliases, a.GetAliasName())
+	}
+	sort.Strings(info.Aliases)
+
+	return info, nil
+
+}
+
+func vectorInfo(v *pb.VectorParams) *VectorInfo {
+	return &VectorInfo{
+		Size:     v.GetSize(),
+		Distance: v.GetDistance().String(),
+		OnDisk:   v.GetOnDisk(),
+	}
+}
+
+// missingCollections returns the names which do not exist on the instance
+func (c *QdrantClient) missingCollections(ctx context.Context, s logical.Storage, dbId string, names []string) ([]string, error) {
+
+	conn, err := c.getClientQdrant(ctx, s, dbId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	client := pb.NewCollectionsClient(conn)
+
+	// Contact the server
+	ctx, cancel := rpcContext(ctx, time.Second)
+	defer cancel()
+
+	var missing []string
+	for _, name := range names {
+		isExists, err := collectionExists(ctx, client, name)
+		if err != nil {
+			return nil, err
+		}
+		if !isExists {
+			missing = append(missing, name)
+		}
+	}
+
+	return missing, nil
 
 }
 
@@ -143,45 +681,44 @@ func loadTLSCredentials(isSecure bool, CA string) (credentials.TransportCredenti
 	return credentials.NewTLS(config), nil
 }
 
-func deleteRolePoint(ctx context.Context, client pb.PointsClient, name string) error {
+func deleteRolePoints(ctx context.Context, client pb.PointsClient, names []string) error {
 
 	// delete role index for sys_roles
 
+	var conditions []*pb.Condition
+	for _, name := range names {
+		conditions = append(conditions, &pb.Condition{
+			ConditionOneOf: &pb.Condition_Field{
+				Field: &pb.FieldCondition{
+					Key: "role",
+					Match: &pb.Match{
+						MatchValue: &pb.Match_Keyword{
+							Keyword: name,
+						},
+					},
+				},
+			},
+		})
+	}
+
 	// filtered search
 	_, err := client.Delete(ctx, &pb.DeletePoints{
-		CollectionName: "sys_roles",
+		CollectionName: SYS_ROLE_TABLE,
 		Points: &pb.PointsSelector{
 			PointsSelectorOneOf: &pb.PointsSelector_Filter{
-
-				&pb.Filter{
-					Should: []*pb.Condition{
-						{
-							ConditionOneOf: &pb.Condition_Field{
-								Field: &pb.FieldCondition{
-									Key: "role",
-									Match: &pb.Match{
-										MatchValue: &pb.Match_Keyword{
-											Keyword: name,
-										},
-									},
-								},
-							},
-						},
-					},
+				Filter: &pb.Filter{
+					Should: conditions,
 				},
 			},
 		},
 	})
 
-	if err != nil {
-		return nil
-	}
-
-	return nil
+	return err
 
 }
 
-func createRolePoint(ctx context.Context, client pb.PointsClient, name string) error {
+// createRoleIndex creates the keyword index of the role payload field
+func createRoleIndex(ctx context.Context, client pb.PointsClient) error {
 
 	// create role index for sys_roles
 	// Create keyword field index
@@ -193,15 +730,17 @@ func createRolePoint(ctx context.Context, client pb.PointsClient, name string) e
 		FieldType:      &fieldIndex1Type,
 	})
 
-	if err != nil {
-		return err
-	}
+	return err
+}
+
+func createRolePoints(ctx context.Context, client pb.PointsClient, names []string) error {
 
 	// create points and insert
 	// Upsert points
 	waitUpsert := true
-	upsertPoints := []*pb.PointStruct{
-		{
+	var upsertPoints []*pb.PointStruct
+	for _, name := range names {
+		upsertPoints = append(upsertPoints, &pb.PointStruct{
 			// Point Id is number or UUID
 			Id: &pb.PointId{
 				PointIdOptions: &pb.PointId_Uuid{Uuid: uuid.New().String()},
@@ -211,11 +750,14 @@ func createRolePoint(ctx context.Context, client pb.PointsClient, name string) e
 				"role": {
 					Kind: &pb.Value_StringValue{StringValue: name},
 				},
+				"managed_by": {
+					Kind: &pb.Value_StringValue{StringValue: registryManagedBy},
+				},
 			},
-		},
+		})
 	}
 
-	_, err = client.Upsert(ctx, &pb.UpsertPoints{
+	_, err := client.Upsert(ctx, &pb.UpsertPoints{
 		CollectionName: SYS_ROLE_TABLE,
 		Wait:           &waitUpsert,
 		Points:         upsertPoints,
@@ -228,6 +770,148 @@ func createRolePoint(ctx context.Context, client pb.PointsClient, name string) e
 
 }
 
+// createCollection creates a collection from the spec and its payload
+// indexes. The collection is dropped again if an index can not be
+// created.
+func (c *QdrantClient) createCollection(ctx context.Context, s logical.Storage, spec *CollectionSpec) error {
+
+	vectors, err := spec.vectorsConfig()
+	if err != nil {
+		return err
+	}
+
+	indexes, err := spec.fieldTypes()
+	if err != nil {
+		return err
+	}
+
+	conn, err := c.getClientQdrant(ctx, s, spec.DBId)
+
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	client := pb.NewCollectionsClient(conn)
+	client_p := pb.NewPointsClient(conn) //PointsClient
+
+	// Contact the server
+	ctx, cancel := rpcContext(ctx, 10*time.Second)
+	defer cancel()
+
+	isExists, err := collectionExists(ctx, client, spec.Name)
+
+	if err != nil {
+		return err
+	}
+
+	if isExists {
+		return conflict(fmt.Sprintf("collection %s already exists", spec.Name))
+	}
+
+	_, err = client.Create(ctx, &pb.CreateCollection{
+		CollectionName: spec.Name,
+		VectorsConfig:  vectors,
+	})
+
+	if err != nil {
+		return fmt.Errorf("Could not create collection %s: %w", spec.Name, err)
+	}
+
+	var fields []string
+	for field := range indexes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	wait := true
+	for _, field := range fields {
+		fieldType := indexes[field]
+		_, err = client_p.CreateFieldIndex(ctx, &pb.CreateFieldIndexCollection{
+			CollectionName: spec.Name,
+			Wait:           &wait,
+			FieldName:      field,
+			FieldType:      &fieldType,
+		})
+
+		if err != nil {
+			client.Delete(ctx, &pb.DeleteCollection{CollectionName: spec.Name})
+			return fmt.Errorf("Could not create index %s: %w", field, err)
+		}
+	}
+
+	return nil
+
+}
+
+// dropCollection deletes a collection if it exists
+func (c *QdrantClient) dropCollection(ctx context.Context, s logical.Storage, dbId string, name string) error {
+
+	conn, err := c.getClientQdrant(ctx, s, dbId)
+
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	client := pb.NewCollectionsClient(conn)
+
+	// Contact the server
+	ctx, cancel := rpcContext(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err = client.Delete(ctx, &pb.DeleteCollection{CollectionName: name})
+
+	if err != nil {
+		return fmt.Errorf("Could not delete collection %s: %w", name, err)
+	}
+
+	return nil
+
+}
+
+// createSnapshot creates a snapshot of the collection
+func (c *QdrantClient) createSnapshot(ctx context.Context, s logical.Storage, dbId string, name string) (*SnapshotRecord, error) {
+
+	conn, err := c.getClientQdrant(ctx, s, dbId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	client := pb.NewSnapshotsClient(conn)
+
+	// Contact the server, snapshots of large collections take a while
+	ctx, cancel := rpcContext(ctx, snapshotTimeout)
+	defer cancel()
+
+	resp, err := client.Create(ctx, &pb.CreateSnapshotRequest{CollectionName: name})
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not create snapshot of collection %s: %w", name, err)
+	}
+
+	snapshot := resp.GetSnapshotDescription()
+
+	record := &SnapshotRecord{
+		Name:      snapshot.GetName(),
+		CreatedAt: time.Now().UTC(),
+		Size:      snapshot.GetSize(),
+		Checksum:  snapshot.GetChecksum(),
+	}
+
+	if snapshot.GetCreationTime() != nil {
+		record.CreatedAt = snapshot.GetCreationTime().AsTime().UTC()
+	}
+
+	return record, nil
+
+}
+
 func createNewCollection(ctx context.Context, client pb.CollectionsClient) error {
 
 	// Create new collection
@@ -255,14 +939,38 @@ func createNewCollection(ctx context.Context, client pb.CollectionsClient) error
 
 }
 
+// ensureRoleCollection creates the role collection if it is missing
+func ensureRoleCollection(ctx context.Context, client pb.CollectionsClient) error {
+
+	isExists, err := checkExistCollection(ctx, client)
+
+	if err != nil {
+		return err
+	}
+
+	if isExists {
+		return nil
+	}
+
+	//create colection
+	return createNewCollection(ctx, client)
+
+}
+
 func checkExistCollection(ctx context.Context, client pb.CollectionsClient) (bool, error) {
 
+	return collectionExists(ctx, client, SYS_ROLE_TABLE)
+
+}
+
+func collectionExists(ctx context.Context, client pb.CollectionsClient, name string) (bool, error) {
+
 	resp, err := client.CollectionExists(ctx, &pb.CollectionExistsRequest{
-		CollectionName: SYS_ROLE_TABLE,
+		CollectionName: name,
 	})
 
 	if err != nil {
-		return false, fmt.Errorf("Could not get collection: %v", err)
+		return false, fmt.Errorf("Could not get collection: %w", err)
 
 	}
 
@@ -270,7 +978,7 @@ func checkExistCollection(ctx context.Context, client pb.CollectionsClient) (boo
 
 }
 
-func getClientQdrant(ctx context.Context, s logical.Storage, dbId string) (*grpc.ClientConn, error) {
+func (c *QdrantClient) getClientQdrant(ctx context.Context, s logical.Storage, dbId string) (*grpc.ClientConn, error) {
 
 	// get stored signing keys
 	config, err := readConfig(ctx, s, dbId)
@@ -285,11 +993,20 @@ func getClientQdrant(ctx context.Context, s logical.Storage, dbId string) (*grpc
 		return nil, err
 	}
 
-	interceptor := interceptorBuilder(config.SignKey)
+	interceptor := interceptorBuilder(dbId, config.SignKey)
 
-	conn, err := grpc.NewClient(config.URL,
+	target := config.URL
+	opts := []grpc.DialOption{
 		grpc.WithTransportCredentials(tlsCredentials),
-		grpc.WithUnaryInterceptor(interceptor))
+		grpc.WithUnaryInterceptor(interceptor),
+	}
+
+	if c != nil && c.dialer != nil {
+		target = "passthrough:///" + config.URL
+		opts = append(opts, grpc.WithContextDialer(c.dialer))
+	}
+
+	conn, err := grpc.NewClient(target, opts...)
 
 	if err != nil {
 		return nil, err
@@ -299,11 +1016,16 @@ func getClientQdrant(ctx context.Context, s logical.Storage, dbId string) (*grpc
 	return conn, nil
 }
 
-func interceptorBuilder(apiKey string) func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+func interceptorBuilder(dbId string, apiKey string) func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
 
 	f := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		ctx, span := traceRPC(ctx, dbId, method)
 		newCtx := metadata.AppendToOutgoingContext(ctx, "api-key", apiKey)
-		return invoker(newCtx, method, req, reply, cc, opts...)
+		err := invoker(newCtx, method, req, reply, cc, opts...)
+		endRPC(span, err)
+		recordRPC(dbId, method, start, err)
+		return err
 	}
 
 	return f
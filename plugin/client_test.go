@@ -0,0 +1,267 @@
+package qdrant
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/migrx-io/vault-plugin-secrets-qdrant/plugin/qdranttest"
+	pb "github.com/qdrant/go-client/qdrant"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func writeTestConfig(t *testing.T, b *QdrantBackend, storage logical.Storage, data map[string]interface{}) {
+	t.Helper()
+
+	config := map[string]interface{}{
+		"url":     "localhost:6334",
+		"sig_key": testAPIKey,
+		"sig_alg": "HS256",
+		"jwt_ttl": "3s",
+	}
+	for k, v := range data {
+		config[k] = v
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "config/instance1",
+		Storage:   storage,
+		Data:      config,
+	})
+	assert.NoError(t, err)
+	assert.False(t, resp.IsError())
+}
+
+func TestRoleSync(t *testing.T) {
+
+	b, reqStorage, srv := getTestBackendWithServer(t)
+	ctx := context.Background()
+
+	writeTestConfig(t, b, reqStorage, nil)
+
+	writeRole := func(name string) error {
+		_, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "role/instance1/" + name,
+			Storage:   reqStorage,
+			Data:      map[string]interface{}{"claims": map[string]interface{}{"access": "r"}},
+		})
+		return err
+	}
+
+	t.Run("create", func(t *testing.T) {
+		assert.NoError(t, writeRole("read"))
+		assert.NoError(t, writeRole("write"))
+
+		assert.Equal(t, []string{SYS_ROLE_TABLE}, srv.Collections())
+		assert.Equal(t, []string{"read", "write"}, srv.PayloadValues(SYS_ROLE_TABLE, "role"))
+		assert.Equal(t, pb.FieldType_FieldTypeKeyword, srv.Indexes(SYS_ROLE_TABLE)["role"])
+
+		// rewriting a role replaces its point
+		assert.NoError(t, writeRole("read"))
+		assert.Equal(t, []string{"read", "write"}, srv.PayloadValues(SYS_ROLE_TABLE, "role"))
+	})
+
+	t.Run("drift", func(t *testing.T) {
+		srv.RemovePoints(SYS_ROLE_TABLE, "role", "read")
+		assert.Equal(t, []string{"write"}, srv.PayloadValues(SYS_ROLE_TABLE, "role"))
+
+		assert.NoError(t, writeRole("read"))
+		assert.Equal(t, []string{"read", "write"}, srv.PayloadValues(SYS_ROLE_TABLE, "role"))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.DeleteOperation,
+			Path:      "role/instance1/write",
+			Storage:   reqStorage,
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+		assert.Equal(t, []string{"read"}, srv.PayloadValues(SYS_ROLE_TABLE, "role"))
+
+		state, err := readRoleSync(ctx, reqStorage, "instance1", "write")
+		assert.NoError(t, err)
+		assert.Nil(t, state)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		srv.FailOnce(qdranttest.MethodPointsUpsert, status.Error(codes.Unavailable, "upsert failed"))
+
+		err := writeRole("write")
+		assertErrorCode(t, err, ErrCodeQdrantUnavailable, http.StatusServiceUnavailable)
+		assert.Contains(t, err.Error(), "upsert failed")
+
+		role, err := readRole(ctx, reqStorage, "instance1", "write")
+		assert.NoError(t, err)
+		assert.Nil(t, role)
+
+		state, err := readRoleSync(ctx, reqStorage, "instance1", "write")
+		assert.NoError(t, err)
+		assert.Nil(t, state)
+
+		// a failed rewrite of a stored role is recorded
+		srv.FailOnce(qdranttest.MethodPointsUpsert, status.Error(codes.Unavailable, "upsert failed"))
+
+		err = writeRole("read")
+		assertErrorCode(t, err, ErrCodeQdrantUnavailable, http.StatusServiceUnavailable)
+
+		state, err = readRoleSync(ctx, reqStorage, "instance1", "read")
+		assert.NoError(t, err)
+		assert.Equal(t, syncStateFailed, state.State)
+		assert.Contains(t, state.LastError, "upsert failed")
+
+		assert.NoError(t, writeRole("read"))
+
+		state, err = readRoleSync(ctx, reqStorage, "instance1", "read")
+		assert.NoError(t, err)
+		assert.Equal(t, syncStateSynced, state.State)
+		assert.Empty(t, state.LastError)
+
+		// the failure is not sticky
+		assert.NoError(t, writeRole("write"))
+		assert.Equal(t, []string{"read", "write"}, srv.PayloadValues(SYS_ROLE_TABLE, "role"))
+
+		// role deletes report the instance failure too
+		srv.FailOnce(qdranttest.MethodPointsDelete, status.Error(codes.Unavailable, "delete failed"))
+
+		_, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.DeleteOperation,
+			Path:      "role/instance1/write",
+			Storage:   reqStorage,
+		})
+		assertErrorCode(t, err, ErrCodeQdrantUnavailable, http.StatusServiceUnavailable)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		srv.Delay(qdranttest.MethodPointsUpsert, 5*time.Second)
+		defer srv.Reset()
+
+		start := time.Now()
+		err := writeRole("slow")
+		assertErrorCode(t, err, ErrCodeQdrantTimeout, http.StatusGatewayTimeout)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+}
+
+func TestClientAPIKey(t *testing.T) {
+
+	b, reqStorage, srv := getTestBackendWithServer(t)
+	ctx := context.Background()
+
+	writeTestConfig(t, b, reqStorage, map[string]interface{}{"sig_key": "another-very-long-256-bit-secret-key"})
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/instance1/read",
+		Storage:   reqStorage,
+		Data:      map[string]interface{}{"claims": map[string]interface{}{"access": "r"}},
+	})
+	assert.Nil(t, resp)
+	assertErrorCode(t, err, ErrCodeQdrantPermissionDenied, http.StatusBadGateway)
+	assert.Empty(t, srv.Collections())
+}
+
+func TestStrictCollectionsDrift(t *testing.T) {
+
+	b, reqStorage, srv := getTestBackendWithServer(t)
+	ctx := context.Background()
+
+	writeTestConfig(t, b, reqStorage, map[string]interface{}{"strict_collections": strictStrict})
+
+	writeRole := func() error {
+		_, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "role/instance1/orders",
+			Storage:   reqStorage,
+			Data: map[string]interface{}{
+				"collections": []interface{}{map[string]interface{}{"name": "orders", "access": "r"}},
+			},
+		})
+		return err
+	}
+
+	err := writeRole()
+	assertErrorCode(t, err, ErrCodeInvalidRequest, http.StatusBadRequest)
+	assert.Contains(t, err.Error(), "orders")
+
+	srv.AddCollection("orders")
+	assert.NoError(t, writeRole())
+}
+
+func TestProvisionCollection(t *testing.T) {
+
+	b, reqStorage, srv := getTestBackendWithServer(t)
+	ctx := context.Background()
+
+	writeTestConfig(t, b, reqStorage, nil)
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "collection/instance1/orders",
+		Storage:   reqStorage,
+		Data: map[string]interface{}{
+			"size":            4,
+			"payload_indexes": map[string]interface{}{"tenant": "keyword"},
+			"auto_role":       true,
+		},
+	})
+	assert.NoError(t, err)
+	assert.False(t, resp.IsError())
+
+	assert.Equal(t, []string{"orders", SYS_ROLE_TABLE}, srv.Collections())
+	assert.Equal(t, pb.FieldType_FieldTypeKeyword, srv.Indexes("orders")["tenant"])
+	assert.Equal(t, []string{"orders-ro", "orders-rw"}, srv.PayloadValues(SYS_ROLE_TABLE, "role"))
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "collections/instance1/orders",
+		Storage:   reqStorage,
+	})
+	assert.NoError(t, err)
+	assert.False(t, resp.IsError())
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "collection/instance1/orders",
+		Storage:   reqStorage,
+	})
+	assert.NoError(t, err)
+	assert.False(t, resp.IsError())
+
+	assert.Equal(t, []string{SYS_ROLE_TABLE}, srv.Collections())
+	assert.Empty(t, srv.PayloadValues(SYS_ROLE_TABLE, "role"))
+
+	// a failed index drops the collection again
+	srv.FailOnce(qdranttest.MethodPointsCreateFieldIndex, status.Error(codes.Internal, "index failed"))
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "collection/instance1/users",
+		Storage:   reqStorage,
+		Data: map[string]interface{}{
+			"size":            4,
+			"payload_indexes": map[string]interface{}{"tenant": "keyword"},
+		},
+	})
+	assert.Nil(t, resp)
+	assertErrorCode(t, err, ErrCodeQdrantError, http.StatusBadGateway)
+	assert.Equal(t, []string{SYS_ROLE_TABLE}, srv.Collections())
+
+	// a collection created outside of Vault is a conflict
+	srv.AddCollection("events")
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "collection/instance1/events",
+		Storage:   reqStorage,
+		Data:      map[string]interface{}{"size": 4},
+	})
+	assert.Nil(t, resp)
+	assertErrorCode(t, err, ErrCodeConflict, http.StatusConflict)
+}
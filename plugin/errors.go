@@ -1,5 +1,15 @@
 package qdrant
 
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
 const (
 
 	// Parameters
@@ -12,6 +22,7 @@ const (
 	ConfigNotFoundError      = "config not found"
 	DeleteConfigFailedError  = "deleting config failed"
 	ListConfigFailedError    = "listing config failed"
+	CloneConfigFailedError   = "cloning config failed"
 
 	// Role
 	AddingRoleFailedError  = "adding role failed"
@@ -20,7 +31,63 @@ const (
 	DeleteRoleFailedError  = "deleting role failed"
 	ListRoleFailedError    = "listing role failed"
 
+	ReadingRoleHistoryFailedError = "reading role history failed"
+	RoleVersionNotFoundError      = "role version not found"
+	RollbackRoleFailedError       = "rolling back role failed"
+	RenameRoleFailedError         = "renaming role failed"
+
+	// Template
+	AddingTemplateFailedError  = "adding template failed"
+	ReadingTemplateFailedError = "reading template failed"
+	TemplateNotFoundError      = "template not found"
+	DeleteTemplateFailedError  = "deleting template failed"
+	ListTemplateFailedError    = "listing template failed"
+
+	// Collections
+	ListCollectionsFailedError        = "listing collections failed"
+	ReadingCollectionFailedError      = "reading collection failed"
+	CollectionNotFoundError           = "collection not found"
+	ProvisionCollectionFailedError    = "provisioning collection failed"
+	DeleteCollectionFailedError       = "deleting collection failed"
+	ReadingCollectionRolesFailedError = "reading collection roles failed"
+
+	// Snapshot
+	CreateSnapshotFailedError   = "creating snapshot failed"
+	ReadingSnapshotsFailedError = "reading snapshots failed"
+	SnapshotNotFoundError       = "snapshot not found"
+
+	// Static credentials
+	ReadingStaticCredsFailedError = "reading static credentials failed"
+	RotateStaticCredsFailedError  = "rotating static credentials failed"
+
+	// Export
+	ExportFailedError = "export failed"
+	ImportFailedError = "import failed"
+
 	ReadingJWTFailedError = "reading JWT failed"
+
+	// Issuance log
+	ReadingIssuedFailedError = "reading issued tokens failed"
+	IssuedTokenNotFoundError = "issued token not found"
+
+	ReadingStatusFailedError = "reading status failed"
+
+	BootstrapFailedError = "bootstrap failed"
+)
+
+// Error codes returned by failed requests
+const (
+	ErrCodeInvalidRequest         = "invalid_request"
+	ErrCodeNotFound               = "not_found"
+	ErrCodeConflict               = "conflict"
+	ErrCodePermissionDenied       = "permission_denied"
+	ErrCodeRateLimited            = "rate_limited"
+	ErrCodeQdrantUnauthenticated  = "qdrant_unauthenticated"
+	ErrCodeQdrantPermissionDenied = "qdrant_permission_denied"
+	ErrCodeQdrantUnavailable      = "qdrant_unavailable"
+	ErrCodeQdrantTimeout          = "qdrant_timeout"
+	ErrCodeQdrantError            = "qdrant_error"
+	ErrCodeInternal               = "internal_error"
 )
 
 func BuildErrResponse(code string, err error) string {
@@ -28,3 +95,152 @@ func BuildErrResponse(code string, err error) string {
 	return code + ":" + err.Error()
 
 }
+
+// Error is a failed request. It implements logical.HTTPCodedError so
+// Vault replies with Status, and its text starts with ErrorCode.
+type Error struct {
+	ErrorCode string
+	Status    int
+	Message   string
+	Err       error
+}
+
+var _ logical.HTTPCodedError = (*Error)(nil)
+
+func (e *Error) Error() string {
+	return e.ErrorCode + ": " + e.text()
+}
+
+func (e *Error) Code() int {
+	return e.Status
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// text is the error message without the code
+func (e *Error) text() string {
+
+	if e.Err == nil {
+		return e.Message
+	}
+
+	cause := e.Err.Error()
+	var inner *Error
+	if errors.As(e.Err, &inner) && inner == e.Err {
+		cause = inner.text()
+	}
+
+	if e.Message == "" {
+		return cause
+	}
+
+	return e.Message + ":" + cause
+}
+
+// newError wraps err with the message. The code and status come from
+// a wrapped Error or gRPC status, other errors are internal errors.
+func newError(message string, err error) *Error {
+
+	code, status := classifyError(err)
+
+	return &Error{
+		ErrorCode: code,
+		Status:    status,
+		Message:   message,
+		Err:       err,
+	}
+}
+
+// invalid marks err as caused by invalid request parameters
+func invalid(err error) *Error {
+	return &Error{ErrorCode: ErrCodeInvalidRequest, Status: http.StatusBadRequest, Err: err}
+}
+
+// notFound returns an Error for a missing object
+func notFound(message string) *Error {
+	return &Error{ErrorCode: ErrCodeNotFound, Status: http.StatusNotFound, Message: message}
+}
+
+// conflict returns an Error for an object conflicting with the
+// expected state
+func conflict(message string) *Error {
+	return &Error{ErrorCode: ErrCodeConflict, Status: http.StatusConflict, Message: message}
+}
+
+// permissionDenied returns an Error for a requester the role is not
+// bound to
+func permissionDenied(message string) *Error {
+	return &Error{ErrorCode: ErrCodePermissionDenied, Status: http.StatusForbidden, Message: message}
+}
+
+// rateLimited returns an Error for a request exceeding a rate limit
+func rateLimited(message string) *Error {
+	return &Error{ErrorCode: ErrCodeRateLimited, Status: http.StatusTooManyRequests, Message: message}
+}
+
+func classifyError(err error) (string, int) {
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e.ErrorCode, e.Status
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrCodeQdrantTimeout, http.StatusGatewayTimeout
+	}
+
+	if s, ok := status.FromError(err); ok && s.Code() != codes.OK {
+		switch s.Code() {
+		case codes.Unauthenticated:
+			return ErrCodeQdrantUnauthenticated, http.StatusBadGateway
+		case codes.PermissionDenied:
+			return ErrCodeQdrantPermissionDenied, http.StatusBadGateway
+		case codes.Unavailable:
+			return ErrCodeQdrantUnavailable, http.StatusServiceUnavailable
+		case codes.DeadlineExceeded, codes.Canceled:
+			return ErrCodeQdrantTimeout, http.StatusGatewayTimeout
+		case codes.NotFound:
+			return ErrCodeNotFound, http.StatusNotFound
+		case codes.AlreadyExists:
+			return ErrCodeConflict, http.StatusConflict
+		case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+			return ErrCodeInvalidRequest, http.StatusBadRequest
+		default:
+			return ErrCodeQdrantError, http.StatusBadGateway
+		}
+	}
+
+	return ErrCodeInternal, http.StatusInternalServerError
+}
+
+// errorResponse fails a request with the message and err
+func errorResponse(message string, err error) (*logical.Response, error) {
+	return nil, newError(message, err)
+}
+
+// invalidRequestResponse fails a request with invalid parameters
+func invalidRequestResponse(message string, err error) (*logical.Response, error) {
+	return nil, &Error{
+		ErrorCode: ErrCodeInvalidRequest,
+		Status:    http.StatusBadRequest,
+		Message:   message,
+		Err:       err,
+	}
+}
+
+// conflictResponse fails a request conflicting with stored objects
+func conflictResponse(message string, err error) (*logical.Response, error) {
+	return nil, &Error{
+		ErrorCode: ErrCodeConflict,
+		Status:    http.StatusConflict,
+		Message:   message,
+		Err:       err,
+	}
+}
+
+// notFoundResponse fails a request for a missing object
+func notFoundResponse(message string) (*logical.Response, error) {
+	return nil, notFound(message)
+}
@@ -0,0 +1,52 @@
+package qdrant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClassifyError(t *testing.T) {
+
+	for _, tc := range []struct {
+		err    error
+		code   string
+		status int
+	}{
+		{errors.New("storage unavailable"), ErrCodeInternal, http.StatusInternalServerError},
+		{invalid(errors.New("invalid claims")), ErrCodeInvalidRequest, http.StatusBadRequest},
+		{fmt.Errorf("role %q: %w", "reader", invalid(errors.New("invalid claims"))), ErrCodeInvalidRequest, http.StatusBadRequest},
+		{notFound(RoleNotFoundError), ErrCodeNotFound, http.StatusNotFound},
+		{fmt.Errorf("clone: %w", notFound(ConfigNotFoundError)), ErrCodeNotFound, http.StatusNotFound},
+		{context.DeadlineExceeded, ErrCodeQdrantTimeout, http.StatusGatewayTimeout},
+		{status.Error(codes.Unauthenticated, "no key"), ErrCodeQdrantUnauthenticated, http.StatusBadGateway},
+		{status.Error(codes.PermissionDenied, "bad key"), ErrCodeQdrantPermissionDenied, http.StatusBadGateway},
+		{status.Error(codes.Unavailable, "down"), ErrCodeQdrantUnavailable, http.StatusServiceUnavailable},
+		{status.Error(codes.DeadlineExceeded, "slow"), ErrCodeQdrantTimeout, http.StatusGatewayTimeout},
+		{fmt.Errorf("Could not get collection: %w", status.Error(codes.NotFound, "missing")), ErrCodeNotFound, http.StatusNotFound},
+		{status.Error(codes.AlreadyExists, "exists"), ErrCodeConflict, http.StatusConflict},
+		{status.Error(codes.InvalidArgument, "bad"), ErrCodeInvalidRequest, http.StatusBadRequest},
+		{status.Error(codes.Internal, "boom"), ErrCodeQdrantError, http.StatusBadGateway},
+	} {
+		code, status := classifyError(tc.err)
+		assert.Equal(t, tc.code, code, tc.err)
+		assert.Equal(t, tc.status, status, tc.err)
+	}
+}
+
+func TestErrorText(t *testing.T) {
+
+	err := newError(AddingRoleFailedError, notFound(ConfigNotFoundError))
+	assert.Equal(t, "not_found: adding role failed:config not found", err.Error())
+	assert.Equal(t, http.StatusNotFound, err.Code())
+	assert.True(t, errors.Is(err, err.Err))
+
+	err = newError(AddingRoleFailedError, status.Error(codes.Unavailable, "connection refused"))
+	assert.Equal(t, "qdrant_unavailable: adding role failed:rpc error: code = Unavailable desc = connection refused", err.Error())
+}
@@ -0,0 +1,126 @@
+package qdrant
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathBootstrap(b *QdrantBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: configPrefix + framework.GenericNameRegex("dbId") + "/bootstrap$",
+			Fields: map[string]*framework.FieldSchema{
+
+				"dbId": {
+					Type:        framework.TypeString,
+					Description: "DB identifier",
+					Required:    false,
+				},
+				"on_disk": {
+					Type:        framework.TypeBool,
+					Description: "Store the vectors of a created sys_roles on disk",
+					Default:     true,
+				},
+				"shard_number": {
+					Type:        framework.TypeInt,
+					Description: "Number of shards of a created sys_roles, the server default if unset",
+				},
+				"replication_factor": {
+					Type:        framework.TypeInt,
+					Description: "Replication factor of a created sys_roles, the server default if unset",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathBootstrapWrite,
+				},
+			},
+			HelpSynopsis:    pathBootstrapHelpSyn,
+			HelpDescription: pathBootstrapHelpDesc,
+		},
+	}
+
+}
+
+func (b *QdrantBackend) pathBootstrapWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	shards := data.Get("shard_number").(int)
+	replicas := data.Get("replication_factor").(int)
+	if shards < 0 || replicas < 0 {
+		return invalidRequestResponse(InvalidParametersError, errors.New("shard_number and replication_factor can not be negative"))
+	}
+
+	spec := &RegistrySpec{
+		OnDisk:            data.Get("on_disk").(bool),
+		ShardNumber:       uint32(shards),
+		ReplicationFactor: uint32(replicas),
+	}
+
+	result, err := b.bootstrap(ctx, req.Storage, data.Get("dbId").(string), spec)
+	if err != nil {
+		return errorResponse(BootstrapFailedError, err)
+	}
+
+	rval := map[string]interface{}{}
+	err = StructToMap(result, &rval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: rval,
+	}, nil
+}
+
+// bootstrap sets up the registry of an instance and records its layout,
+// after which role writes no longer check the collection and its index
+func (b *QdrantBackend) bootstrap(ctx context.Context, storage logical.Storage, dbId string, spec *RegistrySpec) (*BootstrapResult, error) {
+
+	config, err := readConfig(ctx, storage, dbId)
+	if err != nil {
+		return nil, err
+	}
+
+	if config == nil {
+		return nil, notFound(ConfigNotFoundError)
+	}
+
+	result, err := b.registry.Bootstrap(ctx, storage, dbId, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordRegistryLayout(ctx, storage, dbId, result.Layout)
+	if err != nil {
+		return nil, err
+	}
+
+	b.Logger().Info("bootstrapped registry", "instance", dbId, "created", result.Created, "migrated_points", result.MigratedPoints)
+
+	return result, nil
+}
+
+const pathBootstrapHelpSyn = `
+Set up the role registry of an instance.
+`
+
+const pathBootstrapHelpDesc = `
+Writing config/<instance>/bootstrap creates the sys_roles collection
+with on_disk, shard_number and replication_factor if it does not exist,
+checks it has a single vector of size 1, creates its missing payload
+indexes and marks points written by earlier versions with the current
+payload fields. Bootstrap can be run again at any time.
+
+Once an instance is bootstrapped, role writes no longer check for the
+collection or recreate its index. If sys_roles is deleted afterwards,
+role writes fail until the instance is bootstrapped again. Changing the
+url of the instance clears its bootstrap state.
+`
@@ -0,0 +1,113 @@
+package qdrant
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/migrx-io/vault-plugin-secrets-qdrant/plugin/qdranttest"
+	pb "github.com/qdrant/go-client/qdrant"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBootstrap(t *testing.T) {
+
+	b, reqStorage, srv := getTestBackendWithServer(t)
+	ctx := context.Background()
+
+	bootstrap := func() (*BootstrapResult, error) {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config/instance1/bootstrap",
+			Storage:   reqStorage,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var result BootstrapResult
+		MapToStruct(resp.Data, &result)
+		return &result, nil
+	}
+
+	writeRole := func(name string) error {
+		_, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "role/instance1/" + name,
+			Storage:   reqStorage,
+			Data:      map[string]interface{}{"claims": map[string]interface{}{"access": "r"}},
+		})
+		return err
+	}
+
+	_, err := bootstrap()
+	assertErrorCode(t, err, ErrCodeNotFound, http.StatusNotFound)
+
+	writeTestConfig(t, b, reqStorage, nil)
+
+	// sys_roles created by an earlier version
+	srv.AddCollection(SYS_ROLE_TABLE)
+	srv.AddPoint(SYS_ROLE_TABLE, map[string]string{"role": "legacy"})
+
+	result, err := bootstrap()
+	assert.NoError(t, err)
+	assert.False(t, result.Created)
+	assert.Equal(t, []string{"role"}, result.IndexesCreated)
+	assert.Equal(t, uint64(1), result.MigratedPoints)
+	assert.Equal(t, registryLayout, result.Layout)
+	assert.Equal(t, []string{registryManagedBy}, srv.PayloadValues(SYS_ROLE_TABLE, "managed_by"))
+
+	state, err := readInstanceState(ctx, reqStorage, "instance1")
+	assert.NoError(t, err)
+	assert.Equal(t, registryLayout, state.RegistryLayout)
+	assert.NotNil(t, state.Bootstrapped)
+
+	// bootstrap again changes nothing
+	result, err = bootstrap()
+	assert.NoError(t, err)
+	assert.Empty(t, result.IndexesCreated)
+	assert.Zero(t, result.MigratedPoints)
+
+	// role writes skip the collection and index checks
+	srv.Reset()
+	assert.NoError(t, writeRole("read"))
+	assert.Zero(t, srv.Calls(qdranttest.MethodCollectionExists))
+	assert.Zero(t, srv.Calls(qdranttest.MethodPointsCreateFieldIndex))
+	assert.Equal(t, []string{registryManagedBy, registryManagedBy}, srv.PayloadValues(SYS_ROLE_TABLE, "managed_by"))
+
+	// a deleted sys_roles is not recreated by role writes
+	err = b.client.dropCollection(ctx, reqStorage, "instance1", SYS_ROLE_TABLE)
+	assert.NoError(t, err)
+
+	err = writeRole("write")
+	assert.ErrorContains(t, err, "bootstrap the instance again")
+
+	result, err = bootstrap()
+	assert.NoError(t, err)
+	assert.True(t, result.Created)
+	assert.Equal(t, []string{"role"}, result.IndexesCreated)
+	assert.Equal(t, map[string]pb.FieldType{"role": pb.FieldType_FieldTypeKeyword}, srv.Indexes(SYS_ROLE_TABLE))
+
+	assert.NoError(t, writeRole("write"))
+
+	// changing the url clears the bootstrap state
+	writeTestConfig(t, b, reqStorage, map[string]interface{}{"url": "localhost:6335"})
+
+	state, err = readInstanceState(ctx, reqStorage, "instance1")
+	assert.NoError(t, err)
+	assert.Zero(t, state.RegistryLayout)
+	assert.Nil(t, state.Bootstrapped)
+
+	srv.Reset()
+	assert.NoError(t, writeRole("other"))
+	assert.NotZero(t, srv.Calls(qdranttest.MethodCollectionExists))
+
+	// sys_roles with a different layout is not changed
+	srv.AddCollectionWithVectors(SYS_ROLE_TABLE, &pb.VectorsConfig{Config: &pb.VectorsConfig_Params{
+		Params: &pb.VectorParams{Size: 4, Distance: pb.Distance_Cosine},
+	}})
+
+	_, err = bootstrap()
+	assertErrorCode(t, err, ErrCodeConflict, http.StatusConflict)
+}
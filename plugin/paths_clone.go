@@ -0,0 +1,404 @@
+package qdrant
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+var nameRegex = regexp.MustCompile("^" + framework.GenericNameRegex("name") + "$")
+
+func pathClone(b *QdrantBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: configPrefix + framework.GenericNameRegex("dbId") + "/clone$",
+			Fields: map[string]*framework.FieldSchema{
+
+				"dbId": {
+					Type:        framework.TypeString,
+					Description: "DB identifier",
+					Required:    false,
+				},
+				"target": {
+					Type:        framework.TypeString,
+					Description: "DB identifier of the new instance",
+					Required:    true,
+				},
+				"url": {
+					Type:        framework.TypeString,
+					Description: `Connection string to the new Qdrant database. Defaults to the source url.`,
+				},
+				"sig_Key": {
+					Type:        framework.TypeString,
+					Description: `API Key/ Sign key of the new Qdrant database. Defaults to the source key.`,
+				},
+				"read_only_key": {
+					Type:        framework.TypeString,
+					Description: `Read-only API key of the new Qdrant database. Defaults to the source key unless the url changes.`,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathCloneConfig,
+				},
+			},
+			HelpSynopsis:    pathCloneHelpSyn,
+			HelpDescription: pathCloneHelpDesc,
+		},
+		{
+			Pattern: rolePrefix + framework.GenericNameRegex("dbId") + "/" + framework.GenericNameRegex("role") + "/rename$",
+			Fields: map[string]*framework.FieldSchema{
+
+				"dbId": {
+					Type:        framework.TypeString,
+					Description: "DB identifier",
+					Required:    false,
+				},
+				"role": {
+					Type:        framework.TypeString,
+					Description: "Role name",
+					Required:    false,
+				},
+				"new_name": {
+					Type:        framework.TypeString,
+					Description: "New role name",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathRenameRole,
+				},
+			},
+			HelpSynopsis:    pathRenameHelpSyn,
+			HelpDescription: pathRenameHelpDesc,
+		},
+	}
+
+}
+
+func (b *QdrantBackend) pathCloneConfig(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	err = b.cloneConfig(ctx, req.Storage, data.Get("dbId").(string), data.Get("target").(string), data.Get("url").(string), data.Get("sig_Key").(string), data.Get("read_only_key").(string), req.EntityID)
+
+	if err != nil {
+		return errorResponse(CloneConfigFailedError, err)
+	}
+	return nil, nil
+}
+
+func (b *QdrantBackend) pathRenameRole(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	err = b.renameRole(ctx, req.Storage, data.Get("dbId").(string), data.Get("role").(string), data.Get("new_name").(string), req.EntityID)
+
+	if err != nil {
+		return errorResponse(RenameRoleFailedError, err)
+	}
+	return nil, nil
+}
+
+// cloneConfig copies the config, templates and roles of an instance to
+// a new instance and pushes the roles to the new instance's registry.
+// The read-only key is only copied when the clone keeps the source url.
+func (b *QdrantBackend) cloneConfig(ctx context.Context, storage logical.Storage, dbId string, target string, url string, signKey string, readOnlyKey string, entityID string) error {
+
+	if !isValidName(target) {
+		return invalid(fmt.Errorf("invalid target %q", target))
+	}
+
+	config, err := readConfig(ctx, storage, dbId)
+	if err != nil {
+		return err
+	}
+
+	if config == nil {
+		return notFound(ConfigNotFoundError)
+	}
+
+	existing, err := readConfig(ctx, storage, target)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		return conflict(fmt.Sprintf("config %q already exists", target))
+	}
+
+	staged := newStagedStorage(storage)
+
+	clone := *config
+	clone.DBId = target
+	if url != "" && url != config.URL {
+		clone.URL = url
+		clone.ReadOnlyKey = ""
+	}
+	if signKey != "" {
+		clone.SignKey = signKey
+	}
+	if readOnlyKey != "" {
+		clone.ReadOnlyKey = readOnlyKey
+	}
+
+	err = storeInStorage[ConfigParameters](ctx, staged, configPrefix+target, &clone)
+	if err != nil {
+		return err
+	}
+
+	templates, err := listTemplate(ctx, storage, dbId)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range templates {
+		template, err := readTemplate(ctx, storage, dbId, name)
+		if err != nil {
+			return err
+		}
+		if template == nil {
+			continue
+		}
+		template.DBId = target
+		err = storeInStorage[TemplateParameters](ctx, staged, templatePrefix+target+"/"+name, template)
+		if err != nil {
+			return err
+		}
+	}
+
+	roles, err := readAllRoles(ctx, storage, dbId)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, role := range roles {
+		role.DBId = target
+		role.Generation = 0
+
+		err = recordRoleVersion(ctx, staged, &clone, role, entityID)
+		if err != nil {
+			return err
+		}
+
+		err = storeInStorage[RoleParameters](ctx, staged, rolePrefix+target+"/"+role.RoleId, role)
+		if err != nil {
+			return err
+		}
+		names = append(names, role.RoleId)
+	}
+
+	// push roles to the new instance, reading its staged config
+	err = b.registry.PutRoles(ctx, staged, target, names)
+	if err != nil {
+		return err
+	}
+
+	err = recordRoleSync(ctx, staged, target, names, nil)
+	if err != nil {
+		return err
+	}
+
+	return staged.commit(ctx)
+}
+
+// renameRole moves a role and its history to a new name, in storage
+// and in the instance registry
+func (b *QdrantBackend) renameRole(ctx context.Context, storage logical.Storage, dbId string, name string, newName string, entityID string) error {
+
+	if !isValidName(newName) {
+		return invalid(fmt.Errorf("invalid new_name %q", newName))
+	}
+
+	config, err := readConfig(ctx, storage, dbId)
+	if err != nil {
+		return err
+	}
+
+	if config == nil {
+		return notFound(ConfigNotFoundError)
+	}
+
+	role, err := readRole(ctx, storage, dbId, name)
+	if err != nil {
+		return err
+	}
+
+	if role == nil {
+		return notFound(RoleNotFoundError)
+	}
+
+	existing, err := readRole(ctx, storage, dbId, newName)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		return conflict(fmt.Sprintf("role %q already exists", newName))
+	}
+
+	dependents, err := roleDependents(ctx, storage, dbId, name)
+	if err != nil {
+		return err
+	}
+
+	if len(dependents) > 0 {
+		return conflict("role is inherited by roles: " + strings.Join(dependents, ", "))
+	}
+
+	// keep the history of a deleted role with the new name
+	previous, err := readRoleHistory(ctx, storage, dbId, newName)
+	if err != nil {
+		return err
+	}
+
+	if previous != nil {
+		return conflict(fmt.Sprintf("role %q has the history of a deleted role", newName))
+	}
+
+	staged := newStagedStorage(storage)
+
+	// move history and record the renamed role as a new version
+	history, err := readRoleHistory(ctx, storage, dbId, name)
+	if err != nil {
+		return err
+	}
+
+	if history != nil {
+		err = storeInStorage[RoleHistory](ctx, staged, historyPrefix+dbId+"/"+newName, history)
+		if err != nil {
+			return err
+		}
+	}
+
+	staged.Delete(ctx, historyPrefix+dbId+"/"+name)
+	staged.Delete(ctx, syncPrefix+dbId+"/"+name)
+	staged.Delete(ctx, staticCredsPrefix+dbId+"/"+name)
+	staged.Delete(ctx, rolePrefix+dbId+"/"+name)
+
+	err = moveRateLimits(ctx, staged, dbId, name, newName)
+	if err != nil {
+		return err
+	}
+
+	renamed := *role
+	renamed.RoleId = newName
+	renamed.Claims = renameRoleClaims(role.Claims, name, newName)
+
+	err = recordRoleVersion(ctx, staged, config, &renamed, entityID)
+	if err != nil {
+		return err
+	}
+
+	err = storeInStorage[RoleParameters](ctx, staged, rolePrefix+dbId+"/"+newName, &renamed)
+	if err != nil {
+		return err
+	}
+
+	// add the new registry entry before removing the old one
+	err = b.registry.PutRoles(ctx, storage, dbId, []string{newName})
+	if err != nil {
+		return err
+	}
+
+	err = b.registry.DeleteRoles(ctx, storage, dbId, []string{name})
+	if err != nil {
+		b.registry.DeleteRoles(ctx, storage, dbId, []string{newName})
+		return err
+	}
+
+	err = recordRoleSync(ctx, staged, dbId, []string{newName}, nil)
+	if err != nil {
+		return err
+	}
+
+	err = staged.commit(ctx)
+	if err != nil {
+		// restore the registry entries
+		b.registry.PutRoles(ctx, storage, dbId, []string{name})
+		b.registry.DeleteRoles(ctx, storage, dbId, []string{newName})
+		return err
+	}
+
+	return nil
+}
+
+// renameRoleClaims returns the claims with 'value_exists' matches on
+// the sys_roles role name moved to the new name
+func renameRoleClaims(claims map[string]interface{}, name string, newName string) map[string]interface{} {
+
+	valueExists, ok := claims["value_exists"].(map[string]interface{})
+	if !ok || valueExists["collection"] != SYS_ROLE_TABLE {
+		return claims
+	}
+
+	matches, ok := valueExists["matches"].([]interface{})
+	if !ok {
+		return claims
+	}
+
+	var renamed []interface{}
+	for _, v := range matches {
+		if m, ok := v.(map[string]interface{}); ok && m["key"] == "role" && m["value"] == name {
+			v = map[string]interface{}{"key": "role", "value": newName}
+		}
+		renamed = append(renamed, v)
+	}
+
+	out := map[string]interface{}{}
+	for k, v := range claims {
+		out[k] = v
+	}
+
+	exists := map[string]interface{}{}
+	for k, v := range valueExists {
+		exists[k] = v
+	}
+	exists["matches"] = renamed
+	out["value_exists"] = exists
+
+	return out
+}
+
+func isValidName(name string) bool {
+	return name != "" && nameRegex.MatchString(name)
+}
+
+const pathCloneHelpSyn = `
+Clone an instance.
+`
+
+const pathCloneHelpDesc = `
+Copy the config, templates and roles of an instance to a new instance
+and push the roles to the new instance.
+
+target:            DB identifier of the new instance.
+url:               Connection string of the new instance (defaults to the source url).
+sig_Key:           API Key/ Sign key of the new instance (defaults to the source key).
+read_only_key:     Read-only API key of the new instance (defaults to the source key
+                   unless the url changes).
+`
+
+const pathRenameHelpSyn = `
+Rename a role.
+`
+
+const pathRenameHelpDesc = `
+Move a role and its versions to a new name, in Vault and in the
+instance's sys_roles collection. 'value_exists' claims matching the
+role name in sys_roles are moved to the new name.
+
+new_name:          New role name.
+`
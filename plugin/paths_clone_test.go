@@ -0,0 +1,148 @@
+package qdrant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloneRename(t *testing.T) {
+
+	b, reqStorage := getTestBackend(t)
+
+	t.Run("Test clone and rename validation", func(t *testing.T) {
+
+		ctx := context.Background()
+
+		for _, instance := range []string{"instance1", "instance2"} {
+			resp, err := b.HandleRequest(ctx, &logical.Request{
+				Operation: logical.CreateOperation,
+				Path:      "config/" + instance,
+				Storage:   reqStorage,
+				Data: map[string]interface{}{
+					"url":     "localhost:6334",
+					"sig_key": "your-very-long-256-bit-secret-key",
+					"sig_alg": "HS256",
+					"jwt_ttl": "3s",
+				},
+			})
+			assert.NoError(t, err)
+			assert.False(t, resp.IsError())
+		}
+
+		for _, role := range []*RoleParameters{
+			{DBId: "instance1", RoleId: "read", Claims: map[string]interface{}{"access": "r"}},
+			{DBId: "instance1", RoleId: "reader", Inherit: []string{"read"}},
+		} {
+			err := storeInStorage(ctx, reqStorage, "role/"+role.DBId+"/"+role.RoleId, role)
+			assert.NoError(t, err)
+		}
+
+		// clone to an existing instance
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config/instance1/clone",
+			Storage:   reqStorage,
+			Data:      map[string]interface{}{"target": "instance2"},
+		})
+		assert.Error(t, err)
+		assert.Nil(t, resp)
+
+		// rename to an existing role
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "role/instance1/reader/rename",
+			Storage:   reqStorage,
+			Data:      map[string]interface{}{"new_name": "read"},
+		})
+		assert.Error(t, err)
+		assert.Nil(t, resp)
+
+		// rename an inherited role
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "role/instance1/read/rename",
+			Storage:   reqStorage,
+			Data:      map[string]interface{}{"new_name": "readonly"},
+		})
+		assert.Error(t, err)
+		assert.Nil(t, resp)
+
+		// storage is unchanged
+		roles, err := listRole(ctx, reqStorage, "instance1")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"read", "reader"}, roles)
+	})
+}
+
+func TestRenameRoleClaims(t *testing.T) {
+	assert := assert.New(t)
+
+	claims := map[string]interface{}{
+		"access": "r",
+		"value_exists": map[string]interface{}{
+			"collection": "sys_roles",
+			"matches": []interface{}{
+				map[string]interface{}{"key": "role", "value": "write"},
+			},
+		},
+	}
+
+	renamed := renameRoleClaims(claims, "write", "writer")
+	assert.Equal(map[string]interface{}{
+		"access": "r",
+		"value_exists": map[string]interface{}{
+			"collection": "sys_roles",
+			"matches": []interface{}{
+				map[string]interface{}{"key": "role", "value": "writer"},
+			},
+		},
+	}, renamed)
+
+	// the original claims are untouched
+	assert.Equal("write", claims["value_exists"].(map[string]interface{})["matches"].([]interface{})[0].(map[string]interface{})["value"])
+}
+
+func TestCloneReadOnlyKey(t *testing.T) {
+
+	b, reqStorage, _ := getTestBackendWithServer(t)
+	ctx := context.Background()
+
+	writeTestConfig(t, b, reqStorage, map[string]interface{}{"read_only_key": "read-only-1"})
+
+	clone := func(data map[string]interface{}) *ConfigParameters {
+		t.Helper()
+
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config/instance1/clone",
+			Storage:   reqStorage,
+			Data:      data,
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+
+		config, err := readConfig(ctx, reqStorage, data["target"].(string))
+		assert.NoError(t, err)
+		return config
+	}
+
+	// the key is kept for the same url
+	config := clone(map[string]interface{}{"target": "copy"})
+	assert.Equal(t, "read-only-1", config.ReadOnlyKey)
+
+	// the key of the source is not handed out for another instance
+	config = clone(map[string]interface{}{"target": "staging", "url": "staging:6334"})
+	assert.Equal(t, "staging:6334", config.URL)
+	assert.Empty(t, config.ReadOnlyKey)
+
+	config = clone(map[string]interface{}{"target": "staging2", "url": "staging:6334", "read_only_key": "read-only-2"})
+	assert.Equal(t, "read-only-2", config.ReadOnlyKey)
+	assert.Equal(t, testAPIKey, config.SignKey)
+
+	// the sign key override uses the config field name
+	config = clone(map[string]interface{}{"target": "staging3", "sig_Key": "other-256-bit-secret-key"})
+	assert.Equal(t, "other-256-bit-secret-key", config.SignKey)
+}
@@ -0,0 +1,365 @@
+package qdrant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	collectionsPrefix = "collections/"
+)
+
+// roleAccess is the resolved access of a role, with its collection
+// patterns kept apart from the exact claims
+type roleAccess struct {
+	Role     *RoleParameters
+	Claims   map[string]interface{}
+	Patterns []CollectionAccess
+}
+
+func pathCollections(b *QdrantBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: collectionsPrefix + framework.GenericNameRegex("dbId") + "/" + framework.GenericNameRegex("collection") + "/roles$",
+			Fields: map[string]*framework.FieldSchema{
+
+				"dbId": {
+					Type:        framework.TypeString,
+					Description: "DB identifier",
+					Required:    false,
+				},
+				"collection": {
+					Type:        framework.TypeString,
+					Description: "Collection name",
+					Required:    false,
+				},
+				"check_instance": {
+					Type:        framework.TypeBool,
+					Description: "Join against the instance collections to report unreachable and missing collections",
+					Query:       true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathReadCollectionRoles,
+				},
+			},
+			HelpSynopsis:    pathCollectionRolesHelpSyn,
+			HelpDescription: pathCollectionRolesHelpDesc,
+		},
+		{
+			Pattern: collectionsPrefix + framework.GenericNameRegex("dbId") + "/" + framework.GenericNameRegex("collection") + "$",
+			Fields: map[string]*framework.FieldSchema{
+
+				"dbId": {
+					Type:        framework.TypeString,
+					Description: "DB identifier",
+					Required:    false,
+				},
+				"collection": {
+					Type:        framework.TypeString,
+					Description: "Collection name",
+					Required:    false,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathReadCollection,
+				},
+			},
+			HelpSynopsis:    pathCollectionsHelpSyn,
+			HelpDescription: pathCollectionsHelpDesc,
+		},
+		{
+			Pattern: collectionsPrefix + framework.GenericNameRegex("dbId") + "/?$",
+			Fields: map[string]*framework.FieldSchema{
+
+				"dbId": {
+					Type:        framework.TypeString,
+					Description: "DB identifier",
+					Required:    false,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.pathListCollections,
+				},
+			},
+			HelpSynopsis:    pathCollectionsHelpSyn,
+			HelpDescription: pathCollectionsHelpDesc,
+		},
+	}
+
+}
+
+func (b *QdrantBackend) pathReadCollectionRoles(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	dbId := data.Get("dbId").(string)
+	collection := data.Get("collection").(string)
+
+	config, err := readConfig(ctx, req.Storage, dbId)
+	if err != nil {
+		return errorResponse(ReadingCollectionRolesFailedError, err)
+	}
+
+	if config == nil {
+		return notFoundResponse(ConfigNotFoundError)
+	}
+
+	accesses, warnings, err := readRoleAccesses(ctx, req.Storage, dbId)
+	if err != nil {
+		return errorResponse(ReadingCollectionRolesFailedError, err)
+	}
+
+	grants, err := collectionGrants(accesses, collection)
+	if err != nil {
+		return errorResponse(ReadingCollectionRolesFailedError, err)
+	}
+
+	rval := map[string]interface{}{}
+	err = StructToMap(&grants, &rval)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"collection": collection,
+			"roles":      rval,
+		},
+	}
+
+	if data.Get("check_instance").(bool) {
+		names, err := b.client.listCollections(ctx, req.Storage, dbId)
+		if err != nil {
+			return errorResponse(ReadingCollectionRolesFailedError, err)
+		}
+
+		unreachable, missing, err := collectionCoverage(accesses, names)
+		if err != nil {
+			return errorResponse(ReadingCollectionRolesFailedError, err)
+		}
+
+		exists := false
+		for _, name := range names {
+			if name == collection {
+				exists = true
+			}
+		}
+
+		resp.Data["exists"] = exists
+		resp.Data["unreachable_collections"] = unreachable
+		resp.Data["missing_collections"] = missing
+	}
+
+	for _, w := range warnings {
+		resp.AddWarning(w)
+	}
+
+	return resp, nil
+}
+
+func (b *QdrantBackend) pathListCollections(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	dbId := data.Get("dbId").(string)
+
+	config, err := readConfig(ctx, req.Storage, dbId)
+	if err != nil {
+		return errorResponse(ListCollectionsFailedError, err)
+	}
+
+	if config == nil {
+		return notFoundResponse(ConfigNotFoundError)
+	}
+
+	names, err := b.client.listCollections(ctx, req.Storage, dbId)
+	if err != nil {
+		return errorResponse(ListCollectionsFailedError, err)
+	}
+	sort.Strings(names)
+
+	infos, err := b.client.describeCollections(ctx, req.Storage, dbId, names)
+	if err != nil {
+		return errorResponse(ListCollectionsFailedError, err)
+	}
+
+	keyInfo := map[string]interface{}{}
+	for _, info := range infos {
+		rval := map[string]interface{}{}
+		err = StructToMap(info, &rval)
+		if err != nil {
+			return nil, err
+		}
+		delete(rval, "name")
+		keyInfo[info.Name] = rval
+	}
+
+	return logical.ListResponseWithInfo(names, keyInfo), nil
+}
+
+func (b *QdrantBackend) pathReadCollection(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	dbId := data.Get("dbId").(string)
+	collection := data.Get("collection").(string)
+
+	config, err := readConfig(ctx, req.Storage, dbId)
+	if err != nil {
+		return errorResponse(ReadingCollectionFailedError, err)
+	}
+
+	if config == nil {
+		return notFoundResponse(ConfigNotFoundError)
+	}
+
+	missing, err := b.client.missingCollections(ctx, req.Storage, dbId, []string{collection})
+	if err != nil {
+		return errorResponse(ReadingCollectionFailedError, err)
+	}
+
+	if len(missing) > 0 {
+		return notFoundResponse(CollectionNotFoundError)
+	}
+
+	infos, err := b.client.describeCollections(ctx, req.Storage, dbId, []string{collection})
+	if err != nil {
+		return errorResponse(ReadingCollectionFailedError, err)
+	}
+
+	rval := map[string]interface{}{}
+	err = StructToMap(infos[0], &rval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: rval,
+	}, nil
+}
+
+// readRoleAccesses resolves the access of every role of an instance.
+// Roles which can not be resolved are returned as warnings.
+func readRoleAccesses(ctx context.Context, storage logical.Storage, dbId string) ([]roleAccess, []string, error) {
+
+	roles, err := readAllRoles(ctx, storage, dbId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var accesses []roleAccess
+	var warnings []string
+	for _, role := range roles {
+		claims, patterns, err := resolvedAccess(ctx, storage, role)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("role %q skipped: %s", role.RoleId, err))
+			continue
+		}
+		accesses = append(accesses, roleAccess{Role: role, Claims: claims, Patterns: patterns})
+	}
+
+	return accesses, warnings, nil
+}
+
+// collectionGrants returns the effective access of every role granting
+// access to the collection
+func collectionGrants(accesses []roleAccess, collection string) (map[string]*CollectionGrant, error) {
+
+	if collection == "" {
+		return nil, invalid(errors.New("missing collection"))
+	}
+
+	grants := map[string]*CollectionGrant{}
+	for _, a := range accesses {
+		grant, err := effectiveAccess(a.Claims, a.Patterns, collection)
+		if err != nil {
+			return nil, fmt.Errorf("role %q: %w", a.Role.RoleId, err)
+		}
+		if grant != nil {
+			grants[a.Role.RoleId] = grant
+		}
+	}
+
+	return grants, nil
+}
+
+// collectionCoverage joins the roles against the instance collections.
+// It returns the collections no role can reach and, per role, the
+// collections referenced by name which do not exist.
+func collectionCoverage(accesses []roleAccess, names []string) ([]string, map[string][]string, error) {
+
+	existing := map[string]bool{}
+	for _, name := range names {
+		existing[name] = true
+	}
+
+	unreachable := []string{}
+	for _, name := range names {
+		if name == SYS_ROLE_TABLE {
+			continue
+		}
+
+		grants, err := collectionGrants(accesses, name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(grants) == 0 {
+			unreachable = append(unreachable, name)
+		}
+	}
+	sort.Strings(unreachable)
+
+	missing := map[string][]string{}
+	for _, a := range accesses {
+		for _, name := range referencedCollections(&RoleParameters{Claims: a.Claims}) {
+			if !existing[name] {
+				missing[a.Role.RoleId] = append(missing[a.Role.RoleId], name)
+			}
+		}
+	}
+
+	return unreachable, missing, nil
+}
+
+const pathCollectionRolesHelpSyn = `
+List the roles granting access to a collection.
+`
+
+const pathCollectionRolesHelpDesc = `
+Scan the role claims, including collection patterns and global access
+levels, and return the effective access of every role granting access
+to the collection.
+
+check_instance:    Join against the instance collections and report the
+                   collections no role can reach and the collections
+                   referenced by roles which do not exist.
+`
+
+const pathCollectionsHelpSyn = `
+Browse the collections of an instance.
+`
+
+const pathCollectionsHelpDesc = `
+List or read the collections of an instance with the stored API key:
+their status, point counts, vector configs and aliases. The endpoints
+are read-only.
+`
@@ -0,0 +1,169 @@
+package qdrant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	pb "github.com/qdrant/go-client/qdrant"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestCollectionRoles(t *testing.T) {
+
+	b, reqStorage := getTestBackend(t)
+
+	t.Run("Test collection roles", func(t *testing.T) {
+
+		ctx := context.Background()
+
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "config/instance1",
+			Storage:   reqStorage,
+			Data: map[string]interface{}{
+				"url":     "localhost:6334",
+				"sig_key": "your-very-long-256-bit-secret-key",
+				"sig_alg": "HS256",
+				"jwt_ttl": "3s",
+			},
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+
+		for _, role := range []*RoleParameters{
+			{DBId: "instance1", RoleId: "admin", Claims: map[string]interface{}{"access": "m"}},
+			{DBId: "instance1", RoleId: "full", Claims: map[string]interface{}{"value_exists": true}},
+			{DBId: "instance1", RoleId: "events", Collections: []CollectionAccess{{Name: "events_*", Access: "rw", Match: matchGlob}}},
+			{DBId: "instance1", RoleId: "orders", Collections: []CollectionAccess{
+				{Name: "orders", Access: "r", Payload: map[string]interface{}{"tenant": "acme"}},
+				{Name: "orders*", Access: "rw", Match: matchGlob},
+			}},
+			{DBId: "instance1", RoleId: "broken", Inherit: []string{"missing"}},
+		} {
+			err := storeInStorage(ctx, reqStorage, "role/"+role.DBId+"/"+role.RoleId, role)
+			assert.NoError(t, err)
+		}
+
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "collections/instance1/orders/roles",
+			Storage:   reqStorage,
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+		assert.Equal(t, "orders", resp.Data["collection"])
+		assert.Equal(t, map[string]interface{}{
+			"admin": map[string]interface{}{"access": "m", "source": "global"},
+			"full":  map[string]interface{}{"access": "m", "source": "global"},
+			"orders": map[string]interface{}{
+				"access":  "r",
+				"source":  "exact",
+				"payload": map[string]interface{}{"tenant": "acme"},
+			},
+		}, resp.Data["roles"])
+		assert.Len(t, resp.Warnings, 1)
+
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "collections/noinstance/orders/roles",
+			Storage:   reqStorage,
+		})
+		assert.Error(t, err)
+		assert.Nil(t, resp)
+	})
+}
+
+func TestCollectionCoverage(t *testing.T) {
+
+	accesses := []roleAccess{
+		{
+			Role:     &RoleParameters{RoleId: "events"},
+			Claims:   map[string]interface{}{"access": []interface{}{}},
+			Patterns: []CollectionAccess{{Name: "events_*", Access: "rw", Match: matchGlob}},
+		},
+		{
+			Role: &RoleParameters{RoleId: "users"},
+			Claims: map[string]interface{}{"access": []interface{}{
+				map[string]interface{}{"collection": "users", "access": "r"},
+				map[string]interface{}{"collection": "archive", "access": "r"},
+			}},
+		},
+	}
+
+	grants, err := collectionGrants(accesses, "events_2026")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]*CollectionGrant{
+		"events": {Access: "rw", Source: "glob:events_*"},
+	}, grants)
+
+	unreachable, missing, err := collectionCoverage(accesses, []string{"sys_roles", "users", "events_2026", "orders"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"orders"}, unreachable)
+	assert.Equal(t, map[string][]string{"users": {"archive"}}, missing)
+}
+
+// stubCollectionsClient answers collection info and alias requests
+type stubCollectionsClient struct {
+	pb.CollectionsClient
+	info    *pb.CollectionInfo
+	aliases []string
+}
+
+func (c *stubCollectionsClient) Get(ctx context.Context, in *pb.GetCollectionInfoRequest, opts ...grpc.CallOption) (*pb.GetCollectionInfoResponse, error) {
+	return &pb.GetCollectionInfoResponse{Result: c.info}, nil
+}
+
+func (c *stubCollectionsClient) ListCollectionAliases(ctx context.Context, in *pb.ListCollectionAliasesRequest, opts ...grpc.CallOption) (*pb.ListAliasesResponse, error) {
+	var aliases []*pb.AliasDescription
+	for _, a := range c.aliases {
+		aliases = append(aliases, &pb.AliasDescription{AliasName: a, CollectionName: in.CollectionName})
+	}
+	return &pb.ListAliasesResponse{Aliases: aliases}, nil
+}
+
+func TestDescribeCollection(t *testing.T) {
+
+	points := uint64(42)
+	client := &stubCollectionsClient{
+		info: &pb.CollectionInfo{
+			Status:      pb.CollectionStatus_Green,
+			PointsCount: &points,
+			Config: &pb.CollectionConfig{
+				Params: &pb.CollectionParams{
+					VectorsConfig: &pb.VectorsConfig{Config: &pb.VectorsConfig_ParamsMap{
+						ParamsMap: &pb.VectorParamsMap{Map: map[string]*pb.VectorParams{
+							"text":  {Size: 384, Distance: pb.Distance_Cosine},
+							"image": {Size: 512, Distance: pb.Distance_Dot},
+						}},
+					}},
+				},
+			},
+		},
+		aliases: []string{"orders_v2", "orders_latest"},
+	}
+
+	info, err := describeCollection(context.Background(), client, "orders")
+	assert.NoError(t, err)
+	assert.Equal(t, &CollectionInfo{
+		Name:        "orders",
+		Status:      "Green",
+		PointsCount: 42,
+		Vectors: map[string]*VectorInfo{
+			"text":  {Size: 384, Distance: "Cosine"},
+			"image": {Size: 512, Distance: "Dot"},
+		},
+		Aliases: []string{"orders_latest", "orders_v2"},
+	}, info)
+
+	client.info.Config.Params.VectorsConfig = &pb.VectorsConfig{Config: &pb.VectorsConfig_Params{
+		Params: &pb.VectorParams{Size: 4, Distance: pb.Distance_Euclid},
+	}}
+	client.aliases = nil
+
+	info, err = describeCollection(context.Background(), client, "orders")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]*VectorInfo{"default": {Size: 4, Distance: "Euclid"}}, info.Vectors)
+	assert.Equal(t, []string{}, info.Aliases)
+}
@@ -17,13 +17,21 @@ const (
 )
 
 type ConfigParameters struct {
-	DBId               string                  `json:"dbId"`
-	URL                string                  `json:"url"`
-	SignKey            string                  `json:"sig_Key"`
-	SignatureAlgorithm jose.SignatureAlgorithm `json:"sig_alg,omitempty"`
-	TokenTTL           string                  `json:"jwt_ttl,omitempty"`
-	TLS                bool                    `json:"tls,omitempty"`
-	CA                 string                  `json:"ca,omitempty"`
+	DBId                 string                  `json:"dbId"`
+	URL                  string                  `json:"url"`
+	SignKey              string                  `json:"sig_Key"`
+	SignatureAlgorithm   jose.SignatureAlgorithm `json:"sig_alg,omitempty"`
+	TokenTTL             string                  `json:"jwt_ttl,omitempty"`
+	TLS                  bool                    `json:"tls,omitempty"`
+	CA                   string                  `json:"ca,omitempty"`
+	PatternLimit         int                     `json:"pattern_limit,omitempty"`
+	CollectionCacheTTL   string                  `json:"collection_cache_ttl,omitempty"`
+	StrictCollections    string                  `json:"strict_collections,omitempty"`
+	MaxAccess            *AccessPolicy           `json:"max_access,omitempty"`
+	MaxRoleVersions      int                     `json:"max_role_versions,omitempty"`
+	ReadOnlyKey          string                  `json:"read_only_key,omitempty"`
+	IssuanceLog          bool                    `json:"issuance_log,omitempty"`
+	IssuanceLogRetention string                  `json:"issuance_log_retention,omitempty"`
 }
 
 func pathConfig(b *QdrantBackend) []*framework.Path {
@@ -67,6 +75,38 @@ func pathConfig(b *QdrantBackend) []*framework.Path {
 					Type:        framework.TypeString,
 					Description: `Custom CA for TLS to connect to Qdrant database`,
 				},
+				"pattern_limit": {
+					Type:        framework.TypeInt,
+					Description: `Maximum number of collections a role's collection patterns may expand to.`,
+				},
+				"collection_cache_ttl": {
+					Type:        framework.TypeString,
+					Description: `Duration the list of instance collections is cached for pattern expansion.`,
+				},
+				"strict_collections": {
+					Type:        framework.TypeString,
+					Description: `Check that collections referenced by roles exist: 'off', 'strict' or 'warn'.`,
+				},
+				"max_access": {
+					Type:        framework.TypeMap,
+					Description: `Access ceiling for roles: global_levels, collections and allow_manage.`,
+				},
+				"max_role_versions": {
+					Type:        framework.TypeInt,
+					Description: `Number of versions kept for each role.`,
+				},
+				"read_only_key": {
+					Type:        framework.TypeString,
+					Description: `Read-only API key of the Qdrant database handed out by static roles.`,
+				},
+				"issuance_log": {
+					Type:        framework.TypeBool,
+					Description: `Record the tokens issued for the instance under issued/<instance>.`,
+				},
+				"issuance_log_retention": {
+					Type:        framework.TypeString,
+					Description: `Duration issuance log entries are kept after their token expired.`,
+				},
 			},
 			Operations: map[logical.Operation]framework.OperationHandler{
 				logical.CreateOperation: &framework.PathOperation{
@@ -102,7 +142,7 @@ func pathConfig(b *QdrantBackend) []*framework.Path {
 func (b *QdrantBackend) pathAddConfig(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	err := data.Validate()
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(InvalidParametersError, err)), logical.ErrInvalidRequest
+		return invalidRequestResponse(InvalidParametersError, err)
 	}
 
 	jsonString, err := json.Marshal(data.Raw)
@@ -110,14 +150,15 @@ func (b *QdrantBackend) pathAddConfig(ctx context.Context, req *logical.Request,
 	b.Logger().Debug("pathAddConfig", jsonString)
 
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(DecodeFailedError, err)), logical.ErrInvalidRequest
+		return invalidRequestResponse(DecodeFailedError, err)
 	}
 	params := ConfigParameters{}
 	json.Unmarshal(jsonString, &params)
+	params.IssuanceLog = data.Get("issuance_log").(bool)
 
 	err = b.addConfig(ctx, req.Storage, params)
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(AddingConfigFailedError, err)), nil
+		return errorResponse(AddingConfigFailedError, err)
 	}
 	return nil, nil
 }
@@ -126,12 +167,12 @@ func (b *QdrantBackend) pathReadConfig(ctx context.Context, req *logical.Request
 
 	err := data.Validate()
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(InvalidParametersError, err)), logical.ErrInvalidRequest
+		return invalidRequestResponse(InvalidParametersError, err)
 	}
 
 	jsonString, err := json.Marshal(data.Raw)
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(DecodeFailedError, err)), logical.ErrInvalidRequest
+		return invalidRequestResponse(DecodeFailedError, err)
 	}
 	params := ConfigParameters{}
 	json.Unmarshal(jsonString, &params)
@@ -139,11 +180,11 @@ func (b *QdrantBackend) pathReadConfig(ctx context.Context, req *logical.Request
 	config, err := readConfig(ctx, req.Storage, params.DBId)
 
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(ReadingConfigFailedError, err)), nil
+		return errorResponse(ReadingConfigFailedError, err)
 	}
 
 	if config == nil {
-		return logical.ErrorResponse(ConfigNotFoundError), nil
+		return notFoundResponse(ConfigNotFoundError)
 	}
 
 	return createResponseConfig(config)
@@ -154,35 +195,61 @@ func (b *QdrantBackend) pathListConfig(ctx context.Context, req *logical.Request
 
 	err := data.Validate()
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(InvalidParametersError, err)), logical.ErrInvalidRequest
+		return invalidRequestResponse(InvalidParametersError, err)
 	}
 
 	entries, err := listConfig(ctx, req.Storage)
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(ListConfigFailedError, err)), nil
+		return errorResponse(ListConfigFailedError, err)
 	}
 
-	return logical.ListResponse(entries), nil
+	keyInfo := map[string]interface{}{}
+	for _, name := range entries {
+		config, err := readConfig(ctx, req.Storage, name)
+		if err != nil {
+			return errorResponse(ListConfigFailedError, err)
+		}
+		if config == nil {
+			continue
+		}
+
+		state, err := readInstanceState(ctx, req.Storage, name)
+		if err != nil {
+			return errorResponse(ListConfigFailedError, err)
+		}
+
+		info := map[string]interface{}{
+			"url":     config.URL,
+			"tls":     config.TLS,
+			"jwt_ttl": config.TokenTTL,
+		}
+		if state != nil && state.LastVerified != nil {
+			info["last_verified"] = state.LastVerified
+		}
+		keyInfo[name] = info
+	}
+
+	return logical.ListResponseWithInfo(entries, keyInfo), nil
 }
 
 func (b *QdrantBackend) pathDeleteConfig(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 
 	err := data.Validate()
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(InvalidParametersError, err)), logical.ErrInvalidRequest
+		return invalidRequestResponse(InvalidParametersError, err)
 	}
 
 	jsonString, err := json.Marshal(data.Raw)
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(DecodeFailedError, err)), logical.ErrInvalidRequest
+		return invalidRequestResponse(DecodeFailedError, err)
 	}
 	params := ConfigParameters{}
 	json.Unmarshal(jsonString, &params)
 
 	// delete issue and all related nkeys and jwt
-	err = b.deleteConfig(ctx, req.Storage, params)
+	err = b.deleteConfig(ctx, req.Storage, params, req.EntityID)
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(DeleteConfigFailedError, err)), nil
+		return errorResponse(DeleteConfigFailedError, err)
 	}
 	return nil, nil
 
@@ -194,17 +261,42 @@ func (b *QdrantBackend) addConfig(ctx context.Context, storage logical.Storage,
 
 	b.Logger().Debug("add Config path", path)
 
-	//config, err := getFromStorage[ConfigParameters](ctx, storage, path)
-	//if err != nil {
-	//	return nil, err
-	//}
+	err := validateStrictCollections(params.StrictCollections)
+	if err != nil {
+		return invalid(err)
+	}
 
-	err := storeInStorage[ConfigParameters](ctx, storage, path, &params)
+	err = validateAccessPolicy(params.MaxAccess)
+	if err != nil {
+		return invalid(err)
+	}
+
+	err = validateIssuanceLogRetention(params.IssuanceLogRetention)
+	if err != nil {
+		return invalid(err)
+	}
 
+	current, err := readConfig(ctx, storage, params.DBId)
 	if err != nil {
 		return err
 	}
 
+	// a new URL may point to a registry which was never bootstrapped
+	if current != nil && current.URL != params.URL {
+		err = recordRegistryLayout(ctx, storage, params.DBId, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = storeInStorage[ConfigParameters](ctx, storage, path, &params)
+
+	if err != nil {
+		return err
+	}
+
+	b.resetCollections(params.DBId)
+
 	return nil
 
 }
@@ -229,7 +321,7 @@ func listConfig(ctx context.Context, storage logical.Storage) ([]string, error)
 	return configs, nil
 }
 
-func (b *QdrantBackend) deleteConfig(ctx context.Context, storage logical.Storage, params ConfigParameters) error {
+func (b *QdrantBackend) deleteConfig(ctx context.Context, storage logical.Storage, params ConfigParameters, entityID string) error {
 	// get stored signing keys
 	config, err := readConfig(ctx, storage, params.DBId)
 	if err != nil {
@@ -247,7 +339,57 @@ func (b *QdrantBackend) deleteConfig(ctx context.Context, storage logical.Storag
 	}
 
 	for _, v := range entries {
-		b.deleteRole(ctx, storage, params.DBId, v)
+		b.deleteRole(ctx, storage, params.DBId, v, entityID)
+	}
+
+	// delete all associated templates
+	templates, err := listTemplate(ctx, storage, params.DBId)
+	if err != nil {
+		return errors.New(ListTemplateFailedError)
+	}
+
+	for _, v := range templates {
+		deleteFromStorage(ctx, storage, templatePrefix+params.DBId+"/"+v)
+	}
+
+	// forget provisioned collections, the collections are kept
+	provisioned, err := listProvision(ctx, storage, params.DBId)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range provisioned {
+		deleteFromStorage(ctx, storage, provisionPrefix+params.DBId+"/"+v)
+	}
+
+	creds, err := storage.List(ctx, staticCredsPrefix+params.DBId+"/")
+	if err != nil {
+		return err
+	}
+
+	for _, v := range creds {
+		deleteFromStorage(ctx, storage, staticCredsPrefix+params.DBId+"/"+v)
+	}
+
+	snapshots, err := storage.List(ctx, snapshotPrefix+params.DBId+"/")
+	if err != nil {
+		return err
+	}
+
+	for _, v := range snapshots {
+		deleteFromStorage(ctx, storage, snapshotPrefix+params.DBId+"/"+v)
+	}
+
+	err = deleteInstanceRateLimits(ctx, storage, params.DBId)
+	if err != nil {
+		return err
+	}
+
+	b.resetCollections(params.DBId)
+
+	err = deleteInstanceState(ctx, storage, params.DBId)
+	if err != nil {
+		return err
 	}
 
 	// delete config
@@ -280,4 +422,11 @@ url:              Connection string to Qdrant database.
 key:              API Key/ Sign key to sign and verify token.             
 sig_alg:		  Signature algorithm used to sign new tokens.
 jwt_ttl:          Duration before a token expires.
+pattern_limit:    Maximum number of collections matched by role patterns.
+collection_cache_ttl: Duration the instance collections are cached for.
+strict_collections: Check referenced collections exist (off, strict, warn).
+max_access:       Access ceiling for roles (global_levels, collections, allow_manage).
+max_role_versions: Number of versions kept for each role.
+issuance_log:     Record the tokens issued for the instance.
+issuance_log_retention: Duration log entries are kept after their token expired.
 `
@@ -44,9 +44,12 @@ func TestCRUDConfig(t *testing.T) {
 		})
 		assert.NoError(t, err)
 		assert.False(t, resp.IsError())
-		assert.Equal(t, resp.Data, map[string]interface{}{
-			"keys": []string{"instance1"},
-		})
+		assert.Equal(t, []string{"instance1"}, resp.Data["keys"])
+
+		info := resp.Data["key_info"].(map[string]interface{})["instance1"].(map[string]interface{})
+		assert.Equal(t, "localhost:6334", info["url"])
+		assert.Equal(t, true, info["tls"])
+		assert.Nil(t, info["sig_key"])
 
 		// call read
 		resp, err = b.HandleRequest(context.Background(), &logical.Request{
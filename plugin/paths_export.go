@@ -0,0 +1,484 @@
+package qdrant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	exportPrefix = "export/"
+	importPrefix = "import/"
+
+	exportVersion = 1
+
+	importMerge   = "merge"
+	importReplace = "replace"
+)
+
+// ExportDocument is a versioned snapshot of the roles and templates
+// of an instance, with an optional redacted config
+type ExportDocument struct {
+	Version   int                  `json:"version"`
+	DBId      string               `json:"dbId"`
+	Config    *ConfigParameters    `json:"config,omitempty"`
+	Templates []TemplateParameters `json:"templates,omitempty"`
+	Roles     []RoleParameters     `json:"roles"`
+}
+
+type ImportParameters struct {
+	DBId     string         `json:"dbId"`
+	Document ExportDocument `json:"document"`
+}
+
+// ImportPlan lists the changes an import applies
+type ImportPlan struct {
+	Create    []string `json:"create"`
+	Update    []string `json:"update"`
+	Delete    []string `json:"delete"`
+	Unchanged []string `json:"unchanged"`
+}
+
+func pathExport(b *QdrantBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: exportPrefix + framework.GenericNameRegex("dbId") + "$",
+			Fields: map[string]*framework.FieldSchema{
+
+				"dbId": {
+					Type:        framework.TypeString,
+					Description: "DB identifier",
+					Required:    false,
+				},
+				"include_config": {
+					Type:        framework.TypeBool,
+					Description: `Include the instance config with the sign key redacted.`,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathReadExport,
+				},
+			},
+			HelpSynopsis:    pathExportHelpSyn,
+			HelpDescription: pathExportHelpDesc,
+		},
+		{
+			Pattern: importPrefix + framework.GenericNameRegex("dbId") + "$",
+			Fields: map[string]*framework.FieldSchema{
+
+				"dbId": {
+					Type:        framework.TypeString,
+					Description: "DB identifier",
+					Required:    false,
+				},
+				"document": {
+					Type:        framework.TypeMap,
+					Description: `Document returned by export.`,
+					Required:    true,
+				},
+				"mode": {
+					Type:        framework.TypeString,
+					Description: `'merge' keeps roles missing from the document, 'replace' deletes them.`,
+					Default:     importMerge,
+				},
+				"dry_run": {
+					Type:        framework.TypeBool,
+					Description: `Only return the plan without applying it.`,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathImport,
+				},
+			},
+			HelpSynopsis:    pathImportHelpSyn,
+			HelpDescription: pathImportHelpDesc,
+		},
+	}
+
+}
+
+func (b *QdrantBackend) pathReadExport(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	dbId := data.Get("dbId").(string)
+
+	doc, err := exportInstance(ctx, req.Storage, dbId, data.Get("include_config").(bool))
+
+	if err != nil {
+		return errorResponse(ExportFailedError, err)
+	}
+
+	if doc == nil {
+		return notFoundResponse(ConfigNotFoundError)
+	}
+
+	rval := map[string]interface{}{}
+	err = StructToMap(doc, &rval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: rval,
+	}, nil
+
+}
+
+func (b *QdrantBackend) pathImport(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	jsonString, err := json.Marshal(data.Raw)
+	if err != nil {
+		return invalidRequestResponse(DecodeFailedError, err)
+	}
+	params := ImportParameters{}
+	err = json.Unmarshal(jsonString, &params)
+	if err != nil {
+		return invalidRequestResponse(DecodeFailedError, err)
+	}
+
+	mode := data.Get("mode").(string)
+	dryRun := data.Get("dry_run").(bool)
+
+	rolesPlan, templatesPlan, warnings, err := b.importInstance(ctx, req.Storage, params.DBId, &params.Document, mode, dryRun, req.EntityID)
+
+	if err != nil {
+		return errorResponse(ImportFailedError, err)
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"dry_run":   dryRun,
+			"mode":      mode,
+			"roles":     rolesPlan,
+			"templates": templatesPlan,
+		},
+	}
+	for _, w := range warnings {
+		resp.AddWarning(w)
+	}
+	return resp, nil
+
+}
+
+func exportInstance(ctx context.Context, storage logical.Storage, dbId string, includeConfig bool) (*ExportDocument, error) {
+
+	config, err := readConfig(ctx, storage, dbId)
+	if err != nil {
+		return nil, err
+	}
+
+	if config == nil {
+		return nil, nil
+	}
+
+	doc := &ExportDocument{
+		Version: exportVersion,
+		DBId:    dbId,
+		Roles:   []RoleParameters{},
+	}
+
+	if includeConfig {
+		redacted := *config
+		redacted.SignKey = ""
+		redacted.ReadOnlyKey = ""
+		doc.Config = &redacted
+	}
+
+	templates, err := listTemplate(ctx, storage, dbId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range templates {
+		template, err := readTemplate(ctx, storage, dbId, name)
+		if err != nil {
+			return nil, err
+		}
+		if template != nil {
+			doc.Templates = append(doc.Templates, *template)
+		}
+	}
+
+	roles, err := readAllRoles(ctx, storage, dbId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, role := range roles {
+		doc.Roles = append(doc.Roles, *role)
+	}
+
+	return doc, nil
+}
+
+// importInstance applies the document to the instance. All changes are
+// staged and validated first, committed to storage and then pushed to
+// Qdrant in a single batch.
+func (b *QdrantBackend) importInstance(ctx context.Context, storage logical.Storage, dbId string, doc *ExportDocument, mode string, dryRun bool, entityID string) (*ImportPlan, *ImportPlan, []string, error) {
+
+	if mode != importMerge && mode != importReplace {
+		return nil, nil, nil, invalid(fmt.Errorf("invalid mode %q (expected %q or %q)", mode, importMerge, importReplace))
+	}
+
+	if doc.Version != exportVersion {
+		return nil, nil, nil, invalid(fmt.Errorf("unsupported document version %d", doc.Version))
+	}
+
+	config, err := readConfig(ctx, storage, dbId)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if config == nil {
+		return nil, nil, nil, notFound(ConfigNotFoundError)
+	}
+
+	staged := newStagedStorage(storage)
+
+	// stage templates
+	templates := map[string]interface{}{}
+	for _, t := range doc.Templates {
+		if t.Name == "" {
+			return nil, nil, nil, invalid(errors.New("template: missing name"))
+		}
+		if !isValidName(t.Name) {
+			return nil, nil, nil, invalid(fmt.Errorf("template: invalid name %q", t.Name))
+		}
+		t.DBId = dbId
+		templates[t.Name] = t
+	}
+
+	templatesPlan, err := stageEntries(ctx, staged, templatePrefix+dbId+"/", templates, mode)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// stage roles
+	roles := map[string]interface{}{}
+	for _, r := range doc.Roles {
+		if r.RoleId == "" {
+			return nil, nil, nil, invalid(errors.New("role: missing name"))
+		}
+		if !isValidName(r.RoleId) {
+			return nil, nil, nil, invalid(fmt.Errorf("role: invalid name %q", r.RoleId))
+		}
+		r.DBId = dbId
+
+		// generations are local to the instance
+		current, err := readRole(ctx, storage, dbId, r.RoleId)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		r.Generation = 0
+		if current != nil {
+			r.Generation = current.Generation
+		}
+
+		roles[r.RoleId] = r
+	}
+
+	rolesPlan, err := stageEntries(ctx, staged, rolePrefix+dbId+"/", roles, mode)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// validate every role against the staged state
+	var warnings []string
+	for _, name := range append(append([]string{}, rolesPlan.Create...), rolesPlan.Update...) {
+		role := roles[name].(RoleParameters)
+		w, err := b.checkRole(ctx, staged, config, &role)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("role %q: %w", name, err)
+		}
+		for _, v := range w {
+			warnings = append(warnings, fmt.Sprintf("role %q: %s", name, v))
+		}
+	}
+
+	// roles kept as is must still resolve without deleted parents or templates
+	for _, name := range rolesPlan.Unchanged {
+		role, err := readRole(ctx, staged, dbId, name)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if role == nil {
+			continue
+		}
+		if _, err := resolveRole(ctx, staged, role); err != nil {
+			return nil, nil, nil, fmt.Errorf("role %q: %w", name, err)
+		}
+	}
+
+	if dryRun {
+		return rolesPlan, templatesPlan, warnings, nil
+	}
+
+	// record a new version of every written role
+	for _, name := range append(append([]string{}, rolesPlan.Create...), rolesPlan.Update...) {
+		role := roles[name].(RoleParameters)
+		err = recordRoleVersion(ctx, staged, config, &role, entityID)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		err = storeInStorage[RoleParameters](ctx, staged, rolePrefix+dbId+"/"+name, &role)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	// record the deletion in the history and drop the state of deleted roles
+	for _, name := range rolesPlan.Delete {
+		err = recordRoleDeletion(ctx, staged, config, dbId, name, entityID)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		staged.Delete(ctx, syncPrefix+dbId+"/"+name)
+		staged.Delete(ctx, staticCredsPrefix+dbId+"/"+name)
+
+		err = deleteRateLimits(ctx, staged, dbId, name)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	err = staged.commit(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// push roles in batches once they are stored, a failed push is
+	// recorded in the sync state of the written roles
+	written := append(append([]string{}, rolesPlan.Create...), rolesPlan.Update...)
+
+	err = b.registry.PutRoles(ctx, storage, dbId, written)
+	if err != nil {
+		return nil, nil, nil, b.recordFailedImportSync(ctx, storage, dbId, written, err)
+	}
+
+	err = recordRoleSync(ctx, storage, dbId, written, nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	err = b.registry.DeleteRoles(ctx, storage, dbId, rolesPlan.Delete)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("import stored, removing deleted roles failed: %w", err)
+	}
+
+	return rolesPlan, templatesPlan, warnings, nil
+}
+
+// recordFailedImportSync records the failed push of the imported roles
+// and returns syncErr
+func (b *QdrantBackend) recordFailedImportSync(ctx context.Context, storage logical.Storage, dbId string, names []string, syncErr error) error {
+
+	err := recordRoleSync(ctx, storage, dbId, names, syncErr)
+	if err != nil {
+		b.Logger().Error("recording failed sync failed", "instance", dbId, "error", err)
+	}
+
+	return fmt.Errorf("import stored, pushing roles failed: %w", syncErr)
+}
+
+// stageEntries stages the entries under prefix and returns the plan.
+// In replace mode existing entries missing from entries are deleted.
+func stageEntries(ctx context.Context, staged *stagedStorage, prefix string, entries map[string]interface{}, mode string) (*ImportPlan, error) {
+
+	plan := &ImportPlan{
+		Create:    []string{},
+		Update:    []string{},
+		Delete:    []string{},
+		Unchanged: []string{},
+	}
+
+	existing, err := staged.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range existing {
+		if _, ok := entries[name]; ok {
+			continue
+		}
+		if mode == importReplace {
+			staged.Delete(ctx, prefix+name)
+			plan.Delete = append(plan.Delete, name)
+		} else {
+			plan.Unchanged = append(plan.Unchanged, name)
+		}
+	}
+
+	var names []string
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		entry, err := logical.StorageEntryJSON(prefix+name, entries[name])
+		if err != nil {
+			return nil, err
+		}
+
+		current, err := staged.Get(ctx, prefix+name)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case current == nil:
+			plan.Create = append(plan.Create, name)
+		case string(current.Value) == string(entry.Value):
+			plan.Unchanged = append(plan.Unchanged, name)
+			continue
+		default:
+			plan.Update = append(plan.Update, name)
+		}
+
+		staged.Put(ctx, entry)
+	}
+
+	sort.Strings(plan.Unchanged)
+
+	return plan, nil
+}
+
+const pathExportHelpSyn = `
+Export the roles of an instance.
+`
+
+const pathExportHelpDesc = `
+Export the roles and templates of an instance as one versioned JSON document.
+
+include_config:    Include the instance config with the sign key redacted.
+`
+
+const pathImportHelpSyn = `
+Import the roles of an instance.
+`
+
+const pathImportHelpDesc = `
+Import a document returned by export. Changes are validated before any
+role is written and pushed to Qdrant in a single batch.
+
+document:          Document returned by export.
+mode:              'merge' keeps roles missing from the document, 'replace' deletes them.
+dry_run:           Only return the create/update/delete plan.
+`
@@ -0,0 +1,249 @@
+package qdrant
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/migrx-io/vault-plugin-secrets-qdrant/plugin/qdranttest"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestExportImport(t *testing.T) {
+
+	b, reqStorage := getTestBackend(t)
+
+	t.Run("Test export and import plan", func(t *testing.T) {
+
+		for _, instance := range []string{"instance1", "instance2"} {
+			resp, err := b.HandleRequest(context.Background(), &logical.Request{
+				Operation: logical.CreateOperation,
+				Path:      "config/" + instance,
+				Storage:   reqStorage,
+				Data: map[string]interface{}{
+					"url":     "localhost:6334",
+					"sig_key": "your-very-long-256-bit-secret-key",
+					"sig_alg": "HS256",
+					"jwt_ttl": "3s",
+				},
+			})
+			assert.NoError(t, err)
+			assert.False(t, resp.IsError())
+		}
+
+		// store roles directly, without syncing
+		for _, role := range []*RoleParameters{
+			{DBId: "instance1", RoleId: "read", Claims: map[string]interface{}{"access": "r"}},
+			{DBId: "instance1", RoleId: "write", Collections: []CollectionAccess{{Name: "users", Access: "rw"}}},
+			{DBId: "instance2", RoleId: "read", Claims: map[string]interface{}{"access": "r"}},
+			{DBId: "instance2", RoleId: "old", Claims: map[string]interface{}{"access": "r"}},
+		} {
+			err := storeInStorage(context.Background(), reqStorage, "role/"+role.DBId+"/"+role.RoleId, role)
+			assert.NoError(t, err)
+		}
+
+		// export
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "export/instance1",
+			Storage:   reqStorage,
+			Data:      map[string]interface{}{"include_config": true},
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+
+		var doc ExportDocument
+		MapToStruct(resp.Data, &doc)
+
+		assert.Equal(t, 1, doc.Version)
+		assert.Equal(t, "", doc.Config.SignKey)
+		assert.Equal(t, "localhost:6334", doc.Config.URL)
+		assert.Len(t, doc.Roles, 2)
+
+		// dry run merge into instance2
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "import/instance2",
+			Storage:   reqStorage,
+			Data: map[string]interface{}{
+				"document": resp.Data,
+				"dry_run":  true,
+			},
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+		assert.Equal(t, &ImportPlan{
+			Create:    []string{"write"},
+			Update:    []string{},
+			Delete:    []string{},
+			Unchanged: []string{"old", "read"},
+		}, resp.Data["roles"])
+
+		// dry run replace into instance2
+		exported, err := exportInstance(context.Background(), reqStorage, "instance1", false)
+		assert.NoError(t, err)
+
+		var docMap map[string]interface{}
+		StructToMap(exported, &docMap)
+
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "import/instance2",
+			Storage:   reqStorage,
+			Data: map[string]interface{}{
+				"document": docMap,
+				"mode":     "replace",
+				"dry_run":  true,
+			},
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+		assert.Equal(t, &ImportPlan{
+			Create:    []string{"write"},
+			Update:    []string{},
+			Delete:    []string{"old"},
+			Unchanged: []string{"read"},
+		}, resp.Data["roles"])
+
+		// dry run does not change storage
+		roles, err := listRole(context.Background(), reqStorage, "instance2")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"old", "read"}, roles)
+
+		// names must be reachable through the role and template paths
+		for key, entry := range map[string]map[string]interface{}{
+			"roles":     {"role": "a/b", "claims": map[string]interface{}{"access": "r"}},
+			"templates": {"name": "a/b", "claims": map[string]interface{}{"access": "r"}},
+		} {
+			bad := map[string]interface{}{
+				"version": 1,
+				key:       []interface{}{entry},
+			}
+			_, err = b.HandleRequest(context.Background(), &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "import/instance2",
+				Storage:   reqStorage,
+				Data:      map[string]interface{}{"document": bad},
+			})
+			assertErrorCode(t, err, ErrCodeInvalidRequest, http.StatusBadRequest)
+		}
+
+		// unsupported document version
+		docMap["version"] = 2
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "import/instance2",
+			Storage:   reqStorage,
+			Data: map[string]interface{}{
+				"document": docMap,
+				"dry_run":  true,
+			},
+		})
+		assert.Error(t, err)
+		assert.Nil(t, resp)
+	})
+}
+
+func TestStagedStorage(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	storage := new(logical.InmemStorage)
+
+	storeInStorage(ctx, storage, "role/instance1/a", &RoleParameters{RoleId: "a"})
+	storeInStorage(ctx, storage, "role/instance1/b", &RoleParameters{RoleId: "b"})
+
+	staged := newStagedStorage(storage)
+	storeInStorage(ctx, staged, "role/instance1/c", &RoleParameters{RoleId: "c"})
+	staged.Delete(ctx, "role/instance1/a")
+
+	keys, err := staged.List(ctx, "role/instance1/")
+	assert.NoError(err)
+	assert.Equal([]string{"b", "c"}, keys)
+
+	// underlying storage is untouched until commit
+	keys, _ = storage.List(ctx, "role/instance1/")
+	assert.Equal([]string{"a", "b"}, keys)
+
+	assert.NoError(staged.commit(ctx))
+
+	keys, _ = storage.List(ctx, "role/instance1/")
+	assert.Equal([]string{"b", "c"}, keys)
+}
+
+func TestImportSync(t *testing.T) {
+
+	b, reqStorage, srv := getTestBackendWithServer(t)
+	ctx := context.Background()
+
+	writeTestConfig(t, b, reqStorage, nil)
+
+	doc := map[string]interface{}{
+		"version": 1,
+		"roles": []interface{}{
+			map[string]interface{}{"role": "read", "claims": map[string]interface{}{"access": "r"}},
+		},
+	}
+
+	importDoc := func() error {
+		_, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "import/instance1",
+			Storage:   reqStorage,
+			Data:      map[string]interface{}{"document": doc, "mode": importReplace, "dry_run": false},
+		})
+		return err
+	}
+
+	// a failed push keeps the import and marks the roles failed
+	srv.FailOnce(qdranttest.MethodPointsUpsert, status.Error(codes.Unavailable, "upsert failed"))
+	assertErrorCode(t, importDoc(), ErrCodeQdrantUnavailable, http.StatusServiceUnavailable)
+
+	role, err := readRole(ctx, reqStorage, "instance1", "read")
+	assert.NoError(t, err)
+	assert.NotNil(t, role)
+
+	state, err := readRoleSync(ctx, reqStorage, "instance1", "read")
+	assert.NoError(t, err)
+	assert.Equal(t, syncStateFailed, state.State)
+	assert.Contains(t, state.LastError, "upsert failed")
+
+	names, err := b.registry.ListRoles(ctx, reqStorage, "instance1")
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+
+	// importing a change pushes the role again
+	doc["roles"] = []interface{}{
+		map[string]interface{}{"role": "read", "claims": map[string]interface{}{"access": "r"}, "jwt_ttl": "10s"},
+	}
+	assert.NoError(t, importDoc())
+
+	state, err = readRoleSync(ctx, reqStorage, "instance1", "read")
+	assert.NoError(t, err)
+	assert.Equal(t, syncStateSynced, state.State)
+
+	names, err = b.registry.ListRoles(ctx, reqStorage, "instance1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"read"}, names)
+
+	// replacing drops the rate limit counters of deleted roles
+	err = storeInStorage(ctx, reqStorage, "ratelimit/instance1/read", &rateLimitState{})
+	assert.NoError(t, err)
+	err = storeInStorage(ctx, reqStorage, "ratelimit/instance1/read/entity-1", &rateLimitState{})
+	assert.NoError(t, err)
+
+	doc["roles"] = []interface{}{}
+	assert.NoError(t, importDoc())
+
+	entries, err := reqStorage.List(ctx, "ratelimit/instance1/")
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// the history of deleted roles records the deletion
+	history, err := readRoleHistory(ctx, reqStorage, "instance1", "read")
+	assert.NoError(t, err)
+	assert.True(t, history.Versions[len(history.Versions)-1].Deleted)
+}
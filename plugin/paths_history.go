@@ -0,0 +1,457 @@
+package qdrant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	historyPrefix = "history/"
+
+	defaultMaxRoleVersions = 10
+)
+
+// RoleVersion is a stored version of a role. Deleting a role records
+// a version with Deleted set and an empty role.
+type RoleVersion struct {
+	Generation int            `json:"generation"`
+	CreatedAt  time.Time      `json:"created_at"`
+	EntityID   string         `json:"entity_id,omitempty"`
+	Deleted    bool           `json:"deleted,omitempty"`
+	Role       RoleParameters `json:"role"`
+}
+
+// RoleHistory holds the latest versions of a role, oldest first
+type RoleHistory struct {
+	Versions []RoleVersion `json:"versions"`
+}
+
+type RoleHistoryParameters struct {
+	DBId       string `json:"dbId"`
+	RoleId     string `json:"role"`
+	Generation int    `json:"generation"`
+}
+
+// RoleChange is a single changed field between two role versions
+type RoleChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+func pathRoleHistory(b *QdrantBackend) []*framework.Path {
+
+	roleFields := map[string]*framework.FieldSchema{
+		"dbId": {
+			Type:        framework.TypeString,
+			Description: "DB identifier",
+			Required:    false,
+		},
+		"role": {
+			Type:        framework.TypeString,
+			Description: "Role name",
+			Required:    false,
+		},
+	}
+
+	return []*framework.Path{
+		{
+			Pattern: rolePrefix + framework.GenericNameRegex("dbId") + "/" + framework.GenericNameRegex("role") + "/versions/?$",
+			Fields:  roleFields,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathListRoleVersions,
+				},
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.pathListRoleVersions,
+				},
+			},
+			HelpSynopsis:    pathRoleHistoryHelpSyn,
+			HelpDescription: pathRoleHistoryHelpDesc,
+		},
+		{
+			Pattern: rolePrefix + framework.GenericNameRegex("dbId") + "/" + framework.GenericNameRegex("role") + "/versions/(?P<generation>\\d+)$",
+			Fields: map[string]*framework.FieldSchema{
+				"dbId": roleFields["dbId"],
+				"role": roleFields["role"],
+				"generation": {
+					Type:        framework.TypeInt,
+					Description: "Role generation",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathReadRoleVersion,
+				},
+			},
+			HelpSynopsis:    pathRoleHistoryHelpSyn,
+			HelpDescription: pathRoleHistoryHelpDesc,
+		},
+		{
+			Pattern: rolePrefix + framework.GenericNameRegex("dbId") + "/" + framework.GenericNameRegex("role") + "/diff$",
+			Fields: map[string]*framework.FieldSchema{
+				"dbId": roleFields["dbId"],
+				"role": roleFields["role"],
+				"from": {
+					Type:        framework.TypeInt,
+					Description: "Generation to compare from. Defaults to the previous generation.",
+				},
+				"to": {
+					Type:        framework.TypeInt,
+					Description: "Generation to compare to. Defaults to the current generation.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathDiffRoleVersions,
+				},
+			},
+			HelpSynopsis:    pathRoleHistoryHelpSyn,
+			HelpDescription: pathRoleHistoryHelpDesc,
+		},
+		{
+			Pattern: rolePrefix + framework.GenericNameRegex("dbId") + "/" + framework.GenericNameRegex("role") + "/rollback$",
+			Fields: map[string]*framework.FieldSchema{
+				"dbId": roleFields["dbId"],
+				"role": roleFields["role"],
+				"generation": {
+					Type:        framework.TypeInt,
+					Description: "Generation to roll back to.",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathRollbackRole,
+				},
+			},
+			HelpSynopsis:    pathRoleHistoryHelpSyn,
+			HelpDescription: pathRoleHistoryHelpDesc,
+		},
+	}
+
+}
+
+func (b *QdrantBackend) pathListRoleVersions(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	jsonString, err := json.Marshal(data.Raw)
+	if err != nil {
+		return invalidRequestResponse(DecodeFailedError, err)
+	}
+	params := RoleHistoryParameters{}
+	json.Unmarshal(jsonString, &params)
+
+	history, err := readRoleHistory(ctx, req.Storage, params.DBId, params.RoleId)
+	if err != nil {
+		return errorResponse(ReadingRoleHistoryFailedError, err)
+	}
+
+	if history == nil {
+		return notFoundResponse(RoleNotFoundError)
+	}
+
+	var keys []string
+	keyInfo := map[string]interface{}{}
+	for _, v := range history.Versions {
+		key := strconv.Itoa(v.Generation)
+		keys = append(keys, key)
+		keyInfo[key] = map[string]interface{}{
+			"created_at": v.CreatedAt,
+			"entity_id":  v.EntityID,
+			"deleted":    v.Deleted,
+		}
+	}
+
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
+func (b *QdrantBackend) pathReadRoleVersion(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	version, err := readRoleVersion(ctx, req.Storage, data.Get("dbId").(string), data.Get("role").(string), data.Get("generation").(int))
+	if err != nil {
+		return errorResponse(ReadingRoleHistoryFailedError, err)
+	}
+
+	if version == nil {
+		return notFoundResponse(RoleVersionNotFoundError)
+	}
+
+	rval := map[string]interface{}{}
+	err = StructToMap(version, &rval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: rval,
+	}, nil
+}
+
+func (b *QdrantBackend) pathDiffRoleVersions(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	dbId := data.Get("dbId").(string)
+	name := data.Get("role").(string)
+
+	history, err := readRoleHistory(ctx, req.Storage, dbId, name)
+	if err != nil {
+		return errorResponse(ReadingRoleHistoryFailedError, err)
+	}
+
+	if history == nil || len(history.Versions) == 0 {
+		return notFoundResponse(RoleNotFoundError)
+	}
+
+	to := data.Get("to").(int)
+	if to == 0 {
+		to = history.Versions[len(history.Versions)-1].Generation
+	}
+
+	from := data.Get("from").(int)
+	if from == 0 {
+		from = to - 1
+	}
+
+	fromVersion := history.version(from)
+	toVersion := history.version(to)
+
+	if fromVersion == nil || toVersion == nil {
+		return notFoundResponse(RoleVersionNotFoundError)
+	}
+
+	changes, err := diffRoles(&fromVersion.Role, &toVersion.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"from":    from,
+			"to":      to,
+			"changes": changes,
+		},
+	}, nil
+}
+
+func (b *QdrantBackend) pathRollbackRole(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	dbId := data.Get("dbId").(string)
+	name := data.Get("role").(string)
+
+	version, err := readRoleVersion(ctx, req.Storage, dbId, name, data.Get("generation").(int))
+	if err != nil {
+		return errorResponse(RollbackRoleFailedError, err)
+	}
+
+	if version == nil {
+		return notFoundResponse(RoleVersionNotFoundError)
+	}
+
+	if version.Deleted {
+		return invalidRequestResponse(RollbackRoleFailedError, fmt.Errorf("generation %d records the deletion of the role", version.Generation))
+	}
+
+	// the rolled back role is written as a new generation
+	warnings, err := b.addRole(ctx, req.Storage, version.Role, req.EntityID)
+	if err != nil {
+		return errorResponse(RollbackRoleFailedError, err)
+	}
+
+	if len(warnings) > 0 {
+		resp := &logical.Response{}
+		for _, w := range warnings {
+			resp.AddWarning(w)
+		}
+		return resp, nil
+	}
+	return nil, nil
+}
+
+func readRoleHistory(ctx context.Context, storage logical.Storage, dbId string, role string) (*RoleHistory, error) {
+	path := historyPrefix + dbId + "/" + role
+
+	return getFromStorage[RoleHistory](ctx, storage, path)
+}
+
+func readRoleVersion(ctx context.Context, storage logical.Storage, dbId string, role string, generation int) (*RoleVersion, error) {
+
+	history, err := readRoleHistory(ctx, storage, dbId, role)
+	if err != nil || history == nil {
+		return nil, err
+	}
+
+	return history.version(generation), nil
+}
+
+func (h *RoleHistory) version(generation int) *RoleVersion {
+	for i := range h.Versions {
+		if h.Versions[i].Generation == generation {
+			return &h.Versions[i]
+		}
+	}
+	return nil
+}
+
+// recordRoleVersion sets the next generation on the role and appends
+// it to the role history, keeping the latest max_role_versions versions
+func recordRoleVersion(ctx context.Context, storage logical.Storage, config *ConfigParameters, role *RoleParameters, entityID string) error {
+	return appendRoleVersion(ctx, storage, config, role, entityID, false)
+}
+
+// recordRoleDeletion appends a deleted version to the role history, so
+// the history and the deleting entity stay readable after the role is
+// deleted
+func recordRoleDeletion(ctx context.Context, storage logical.Storage, config *ConfigParameters, dbId string, name string, entityID string) error {
+	return appendRoleVersion(ctx, storage, config, &RoleParameters{DBId: dbId, RoleId: name}, entityID, true)
+}
+
+func appendRoleVersion(ctx context.Context, storage logical.Storage, config *ConfigParameters, role *RoleParameters, entityID string, deleted bool) error {
+
+	history, err := readRoleHistory(ctx, storage, role.DBId, role.RoleId)
+	if err != nil {
+		return err
+	}
+
+	if history == nil {
+		history = &RoleHistory{}
+	}
+
+	generation := 0
+	if current, err := readRole(ctx, storage, role.DBId, role.RoleId); err != nil {
+		return err
+	} else if current != nil {
+		generation = current.Generation
+	}
+	if n := len(history.Versions); n > 0 && history.Versions[n-1].Generation > generation {
+		generation = history.Versions[n-1].Generation
+	}
+
+	role.Generation = generation + 1
+
+	history.Versions = append(history.Versions, RoleVersion{
+		Generation: role.Generation,
+		CreatedAt:  time.Now().UTC(),
+		EntityID:   entityID,
+		Deleted:    deleted,
+		Role:       *role,
+	})
+
+	limit := config.MaxRoleVersions
+	if limit <= 0 {
+		limit = defaultMaxRoleVersions
+	}
+	if len(history.Versions) > limit {
+		history.Versions = history.Versions[len(history.Versions)-limit:]
+	}
+
+	return storeInStorage[RoleHistory](ctx, storage, historyPrefix+role.DBId+"/"+role.RoleId, history)
+}
+
+// diffRoles returns the changed fields between two roles, nested
+// fields are joined with '.'
+func diffRoles(from *RoleParameters, to *RoleParameters) ([]RoleChange, error) {
+
+	if from == nil || to == nil {
+		return nil, errors.New("missing role version")
+	}
+
+	fromMap := map[string]interface{}{}
+	toMap := map[string]interface{}{}
+
+	if err := StructToMap(from, &fromMap); err != nil {
+		return nil, err
+	}
+	if err := StructToMap(to, &toMap); err != nil {
+		return nil, err
+	}
+
+	delete(fromMap, "generation")
+	delete(toMap, "generation")
+
+	fromFlat := map[string]interface{}{}
+	toFlat := map[string]interface{}{}
+	flattenMap("", fromMap, fromFlat)
+	flattenMap("", toMap, toFlat)
+
+	fields := map[string]bool{}
+	for k := range fromFlat {
+		fields[k] = true
+	}
+	for k := range toFlat {
+		fields[k] = true
+	}
+
+	var keys []string
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	changes := []RoleChange{}
+	for _, k := range keys {
+		if !reflect.DeepEqual(fromFlat[k], toFlat[k]) {
+			changes = append(changes, RoleChange{Field: k, From: fromFlat[k], To: toFlat[k]})
+		}
+	}
+
+	return changes, nil
+}
+
+func flattenMap(prefix string, in map[string]interface{}, out map[string]interface{}) {
+	for k, v := range in {
+		key := k
+		if prefix != "" {
+			key = fmt.Sprintf("%s.%s", prefix, k)
+		}
+		if nested, ok := v.(map[string]interface{}); ok && len(nested) > 0 {
+			flattenMap(key, nested, out)
+			continue
+		}
+		out[key] = v
+	}
+}
+
+const pathRoleHistoryHelpSyn = `
+Manage role versions.
+`
+
+const pathRoleHistoryHelpDesc = `
+Read previous versions of a role, compare them and roll back.
+
+versions:          List the stored versions of the role.
+versions/<n>:      Read version n of the role.
+diff:              Compare generations 'from' and 'to'.
+rollback:          Write 'generation' as a new version of the role.
+
+Deleting a role records a deleted version with the deleting entity and
+keeps the history, so a deleted role can be restored by rolling back to
+an earlier generation.
+`
@@ -0,0 +1,162 @@
+package qdrant
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleHistory(t *testing.T) {
+
+	b, reqStorage := getTestBackend(t)
+
+	t.Run("Test role versions", func(t *testing.T) {
+
+		ctx := context.Background()
+		config := &ConfigParameters{DBId: "instance1", MaxRoleVersions: 2}
+
+		// write three versions of the role without syncing
+		for _, access := range []string{"r", "rw", "r"} {
+			role := &RoleParameters{
+				DBId:        "instance1",
+				RoleId:      "events",
+				Collections: []CollectionAccess{{Name: "events", Access: access}},
+			}
+			err := recordRoleVersion(ctx, reqStorage, config, role, "entity-1")
+			assert.NoError(t, err)
+			err = storeInStorage(ctx, reqStorage, "role/instance1/events", role)
+			assert.NoError(t, err)
+		}
+
+		role, err := readRole(ctx, reqStorage, "instance1", "events")
+		assert.NoError(t, err)
+		assert.Equal(t, 3, role.Generation)
+
+		// only the latest versions are kept
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      "role/instance1/events/versions",
+			Storage:   reqStorage,
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+		assert.Equal(t, []string{"2", "3"}, resp.Data["keys"])
+		assert.Equal(t, "entity-1", resp.Data["key_info"].(map[string]interface{})["3"].(map[string]interface{})["entity_id"])
+
+		// read version
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "role/instance1/events/versions/2",
+			Storage:   reqStorage,
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+
+		var version RoleVersion
+		MapToStruct(resp.Data, &version)
+		assert.Equal(t, 2, version.Generation)
+		assert.Equal(t, "rw", version.Role.Collections[0].Access)
+
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "role/instance1/events/versions/1",
+			Storage:   reqStorage,
+		})
+		assert.Error(t, err)
+		assert.Nil(t, resp)
+
+		// diff previous and current version
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "role/instance1/events/diff",
+			Storage:   reqStorage,
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+		assert.Equal(t, 2, resp.Data["from"])
+		assert.Equal(t, 3, resp.Data["to"])
+		assert.Equal(t, []RoleChange{
+			{
+				Field: "collections",
+				From:  []interface{}{map[string]interface{}{"name": "events", "access": "rw"}},
+				To:    []interface{}{map[string]interface{}{"name": "events", "access": "r"}},
+			},
+		}, resp.Data["changes"])
+	})
+}
+
+func TestDiffRoles(t *testing.T) {
+	assert := assert.New(t)
+
+	changes, err := diffRoles(&RoleParameters{
+		RoleId:     "write",
+		TokenTTL:   "10s",
+		Claims:     map[string]interface{}{"access": "r"},
+		Generation: 1,
+	}, &RoleParameters{
+		RoleId:     "write",
+		TokenTTL:   "10s",
+		Claims:     map[string]interface{}{"access": "m"},
+		Generation: 2,
+	})
+	assert.NoError(err)
+	assert.Equal([]RoleChange{{Field: "claims.access", From: "r", To: "m"}}, changes)
+}
+
+func TestRoleHistoryDelete(t *testing.T) {
+
+	b, reqStorage, _ := getTestBackendWithServer(t)
+	ctx := context.Background()
+
+	writeTestConfig(t, b, reqStorage, nil)
+
+	request := func(operation logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(ctx, &logical.Request{
+			Operation: operation,
+			Path:      path,
+			Storage:   reqStorage,
+			Data:      data,
+			EntityID:  "entity-1",
+		})
+	}
+
+	_, err := request(logical.CreateOperation, "role/instance1/read", map[string]interface{}{"claims": map[string]interface{}{"access": "r"}})
+	assert.NoError(t, err)
+
+	_, err = request(logical.DeleteOperation, "role/instance1/read", nil)
+	assert.NoError(t, err)
+
+	// the history records the deletion and who deleted the role
+	resp, err := request(logical.ListOperation, "role/instance1/read/versions", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, resp.Data["keys"])
+
+	version, err := readRoleVersion(ctx, reqStorage, "instance1", "read", 2)
+	assert.NoError(t, err)
+	assert.True(t, version.Deleted)
+	assert.Equal(t, "entity-1", version.EntityID)
+
+	// the deletion can not be rolled back to, earlier versions restore the role
+	_, err = request(logical.UpdateOperation, "role/instance1/read/rollback", map[string]interface{}{"generation": 2})
+	assertErrorCode(t, err, ErrCodeInvalidRequest, http.StatusBadRequest)
+
+	_, err = request(logical.UpdateOperation, "role/instance1/read/rollback", map[string]interface{}{"generation": 1})
+	assert.NoError(t, err)
+
+	role, err := readRole(ctx, reqStorage, "instance1", "read")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, role.Generation)
+	assert.Equal(t, "r", role.Claims["access"])
+
+	// renaming onto the history of a deleted role is a conflict
+	_, err = request(logical.CreateOperation, "role/instance1/old", map[string]interface{}{"claims": map[string]interface{}{"access": "r"}})
+	assert.NoError(t, err)
+	_, err = request(logical.DeleteOperation, "role/instance1/old", nil)
+	assert.NoError(t, err)
+
+	_, err = request(logical.UpdateOperation, "role/instance1/read/rename", map[string]interface{}{"new_name": "old"})
+	assertErrorCode(t, err, ErrCodeConflict, http.StatusConflict)
+}
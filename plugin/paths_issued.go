@@ -0,0 +1,337 @@
+package qdrant
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	issuedPrefix = "issued/"
+
+	defaultIssuanceLogRetention = 30 * 24 * time.Hour
+
+	// issuancePruneInterval is the minimum time between prunes of the
+	// issuance journals by the periodic function
+	issuancePruneInterval = time.Hour
+)
+
+// IssuanceRecord is a journal entry of a token issued by
+// jwt/<instance>/<role>
+type IssuanceRecord struct {
+	JTI        string    `json:"jti"`
+	Role       string    `json:"role"`
+	EntityID   string    `json:"entity_id,omitempty"`
+	IssuedAt   time.Time `json:"issued_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	ClaimsHash string    `json:"claims_hash"`
+}
+
+func pathIssued(b *QdrantBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: issuedPrefix + framework.GenericNameRegex("dbId") + "/" + framework.GenericNameRegex("jti") + "$",
+			Fields: map[string]*framework.FieldSchema{
+
+				"dbId": {
+					Type:        framework.TypeString,
+					Description: "DB identifier",
+					Required:    false,
+				},
+				"jti": {
+					Type:        framework.TypeString,
+					Description: "Token identifier",
+					Required:    false,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathReadIssued,
+				},
+			},
+			HelpSynopsis:    pathIssuedHelpSyn,
+			HelpDescription: pathIssuedHelpDesc,
+		},
+		{
+			Pattern: issuedPrefix + framework.GenericNameRegex("dbId") + "/?$",
+			Fields: map[string]*framework.FieldSchema{
+
+				"dbId": {
+					Type:        framework.TypeString,
+					Description: "DB identifier",
+					Required:    false,
+				},
+				"role": {
+					Type:        framework.TypeString,
+					Description: "Only list tokens issued for this role",
+					Query:       true,
+				},
+				"since": {
+					Type:        framework.TypeString,
+					Description: "Only list tokens issued after this RFC 3339 time, or within this duration",
+					Query:       true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathListIssued,
+				},
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.pathListIssued,
+				},
+			},
+			HelpSynopsis:    pathIssuedHelpSyn,
+			HelpDescription: pathIssuedHelpDesc,
+		},
+	}
+
+}
+
+func (b *QdrantBackend) pathReadIssued(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	record, err := readIssuance(ctx, req.Storage, data.Get("dbId").(string), data.Get("jti").(string))
+	if err != nil {
+		return errorResponse(ReadingIssuedFailedError, err)
+	}
+
+	if record == nil {
+		return notFoundResponse(IssuedTokenNotFoundError)
+	}
+
+	rval := map[string]interface{}{}
+	err = StructToMap(record, &rval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: rval,
+	}, nil
+}
+
+func (b *QdrantBackend) pathListIssued(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	since, err := parseSince(data.Get("since").(string), time.Now())
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	records, err := listIssuance(ctx, req.Storage, data.Get("dbId").(string), data.Get("role").(string), since)
+	if err != nil {
+		return errorResponse(ReadingIssuedFailedError, err)
+	}
+
+	keys := []string{}
+	keyInfo := map[string]interface{}{}
+	for _, record := range records {
+		info := map[string]interface{}{}
+		err = StructToMap(record, &info)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, record.JTI)
+		keyInfo[record.JTI] = info
+	}
+
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
+// parseSince parses an RFC 3339 time or a duration before now. An
+// empty value returns the zero time.
+func parseSince(since string, now time.Time) (time.Time, error) {
+
+	if since == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return t, nil
+	}
+
+	d, err := time.ParseDuration(since)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("since %q must be an RFC 3339 time or a positive duration", since)
+	}
+
+	return now.Add(-d), nil
+}
+
+func validateIssuanceLogRetention(retention string) error {
+
+	if retention == "" {
+		return nil
+	}
+
+	d, err := time.ParseDuration(retention)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("issuance_log_retention %q must be a positive duration", retention)
+	}
+
+	return nil
+}
+
+// issuanceLogRetention returns how long journal entries are kept after
+// their token expired
+func issuanceLogRetention(config *ConfigParameters) time.Duration {
+
+	d, err := time.ParseDuration(config.IssuanceLogRetention)
+	if err != nil || d <= 0 {
+		return defaultIssuanceLogRetention
+	}
+
+	return d
+}
+
+// newIssuanceRecord adds a jti to the claims and returns the journal
+// entry of the token. The claims hash leaves out exp and jti so tokens
+// with the same grants share it.
+func newIssuanceRecord(role string, claims map[string]interface{}, issuedAt time.Time, expiresAt time.Time) (*IssuanceRecord, error) {
+
+	hashed, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(hashed)
+
+	record := &IssuanceRecord{
+		JTI:        uuid.New().String(),
+		Role:       role,
+		IssuedAt:   issuedAt.UTC(),
+		ExpiresAt:  expiresAt.UTC(),
+		ClaimsHash: hex.EncodeToString(sum[:]),
+	}
+
+	claims["jti"] = record.JTI
+
+	return record, nil
+}
+
+func readIssuance(ctx context.Context, storage logical.Storage, dbId string, jti string) (*IssuanceRecord, error) {
+	return getFromStorage[IssuanceRecord](ctx, storage, issuedPrefix+dbId+"/"+jti)
+}
+
+func storeIssuance(ctx context.Context, storage logical.Storage, dbId string, record *IssuanceRecord) error {
+	return storeInStorage[IssuanceRecord](ctx, storage, issuedPrefix+dbId+"/"+record.JTI, record)
+}
+
+// listIssuance returns the journal entries of an instance issued for
+// role (any role if empty) after since, oldest first
+func listIssuance(ctx context.Context, storage logical.Storage, dbId string, role string, since time.Time) ([]*IssuanceRecord, error) {
+
+	entries, err := storage.List(ctx, issuedPrefix+dbId+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	records := []*IssuanceRecord{}
+	for _, jti := range entries {
+		record, err := readIssuance(ctx, storage, dbId, jti)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			continue
+		}
+		if role != "" && record.Role != role {
+			continue
+		}
+		if record.IssuedAt.Before(since) {
+			continue
+		}
+		records = append(records, record)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].IssuedAt.Before(records[j].IssuedAt)
+	})
+
+	return records, nil
+}
+
+// pruneIssuance removes the journal entries whose token expired more
+// than the instance retention ago. Journals of deleted instances are
+// kept for the default retention. Journals are pruned at most once per
+// issuancePruneInterval.
+func (b *QdrantBackend) pruneIssuance(ctx context.Context, storage logical.Storage, now time.Time) error {
+
+	b.pruneMutex.Lock()
+	due := now.Sub(b.issuancePruned) >= issuancePruneInterval
+	if due {
+		b.issuancePruned = now
+	}
+	b.pruneMutex.Unlock()
+
+	if !due {
+		return nil
+	}
+
+	instances, err := storage.List(ctx, issuedPrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range instances {
+		dbId := entry[:len(entry)-1]
+
+		retention := defaultIssuanceLogRetention
+		config, err := readConfig(ctx, storage, dbId)
+		if err != nil {
+			return err
+		}
+		if config != nil {
+			retention = issuanceLogRetention(config)
+		}
+
+		records, err := listIssuance(ctx, storage, dbId, "", time.Time{})
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			if now.Sub(record.ExpiresAt) <= retention {
+				continue
+			}
+			err = deleteFromStorage(ctx, storage, issuedPrefix+dbId+"/"+record.JTI)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+const pathIssuedHelpSyn = `
+Audit the tokens issued for an instance.
+`
+
+const pathIssuedHelpDesc = `
+With issuance_log set on the instance config, every token issued by
+jwt/<instance>/<role> carries a 'jti' claim and is recorded with its
+role, requesting entity, expiry and a SHA-256 hash of its claims.
+
+Reading or listing issued/<instance> returns the recorded tokens,
+oldest first, filtered by 'role' and by 'since' (an RFC 3339 time or a
+duration such as 24h). Reading issued/<instance>/<jti> returns a single
+entry. Entries are pruned issuance_log_retention (30 days by default)
+after their token expired. The journal of a deleted instance is kept
+for 30 days after its tokens expired.
+`
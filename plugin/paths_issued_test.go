@@ -0,0 +1,147 @@
+package qdrant
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssuanceLog(t *testing.T) {
+
+	b, reqStorage, _ := getTestBackendWithServer(t)
+	ctx := context.Background()
+
+	writeTestConfig(t, b, reqStorage, map[string]interface{}{
+		"issuance_log":           true,
+		"issuance_log_retention": "1h",
+	})
+
+	claims := map[string]interface{}{"claims": map[string]interface{}{"access": "r"}}
+	for _, role := range []string{"read", "write"} {
+		_, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "role/instance1/" + role,
+			Storage:   reqStorage,
+			Data:      claims,
+		})
+		assert.NoError(t, err)
+	}
+
+	issue := func(role string) string {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "jwt/instance1/" + role,
+			Storage:   reqStorage,
+			EntityID:  "entity-1",
+		})
+		assert.NoError(t, err)
+
+		// the token carries the logged jti
+		token, err := jwt.ParseSigned(resp.Data["token"].(string), []jose.SignatureAlgorithm{jose.HS256})
+		assert.NoError(t, err)
+
+		var tokenClaims jwt.Claims
+		assert.NoError(t, token.UnsafeClaimsWithoutVerification(&tokenClaims))
+		assert.Equal(t, resp.Data["jti"], tokenClaims.ID)
+
+		return tokenClaims.ID
+	}
+
+	first := issue("read")
+	second := issue("read")
+	third := issue("write")
+
+	// single entry
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "issued/instance1/" + first,
+		Storage:   reqStorage,
+	})
+	assert.NoError(t, err)
+
+	var record IssuanceRecord
+	MapToStruct(resp.Data, &record)
+	assert.Equal(t, "read", record.Role)
+	assert.Equal(t, "entity-1", record.EntityID)
+	assert.Equal(t, 3*time.Second, record.ExpiresAt.Sub(record.IssuedAt).Round(time.Second))
+	assert.Len(t, record.ClaimsHash, 64)
+
+	// same grants share the claims hash
+	other, err := readIssuance(ctx, reqStorage, "instance1", second)
+	assert.NoError(t, err)
+	assert.Equal(t, record.ClaimsHash, other.ClaimsHash)
+
+	other, err = readIssuance(ctx, reqStorage, "instance1", third)
+	assert.NoError(t, err)
+	assert.NotEqual(t, record.ClaimsHash, other.ClaimsHash)
+
+	// query by role and time
+	query := func(data map[string]interface{}) []string {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "issued/instance1",
+			Storage:   reqStorage,
+			Data:      data,
+		})
+		assert.NoError(t, err)
+		keys, _ := resp.Data["keys"].([]string)
+		return keys
+	}
+
+	assert.ElementsMatch(t, []string{first, second, third}, query(nil))
+	assert.ElementsMatch(t, []string{first, second}, query(map[string]interface{}{"role": "read"}))
+	assert.ElementsMatch(t, []string{first, second}, query(map[string]interface{}{"role": "read", "since": "1h"}))
+	assert.Empty(t, query(map[string]interface{}{"since": time.Now().Add(time.Minute).Format(time.RFC3339)}))
+
+	_, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "issued/instance1",
+		Storage:   reqStorage,
+		Data:      map[string]interface{}{"since": "yesterday"},
+	})
+	assertErrorCode(t, err, ErrCodeInvalidRequest, http.StatusBadRequest)
+
+	_, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "issued/instance1/unknown",
+		Storage:   reqStorage,
+	})
+	assertErrorCode(t, err, ErrCodeNotFound, http.StatusNotFound)
+
+	// entries are pruned once their token expired more than the
+	// retention ago, at most once per interval
+	now := time.Now()
+	assert.NoError(t, b.pruneIssuance(ctx, reqStorage, now))
+	assert.Len(t, query(nil), 3)
+
+	b.issuancePruned = now.Add(time.Hour)
+	assert.NoError(t, b.pruneIssuance(ctx, reqStorage, now.Add(90*time.Minute)))
+	assert.Len(t, query(nil), 3)
+
+	assert.NoError(t, b.pruneIssuance(ctx, reqStorage, now.Add(2*time.Hour)))
+	assert.Empty(t, query(nil))
+
+	// deleting the instance keeps its log for the default retention
+	issue("read")
+	_, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "config/instance1",
+		Storage:   reqStorage,
+	})
+	assert.NoError(t, err)
+
+	assert.Len(t, query(nil), 1)
+
+	later := now.Add(2*time.Hour + issuancePruneInterval)
+	assert.NoError(t, b.pruneIssuance(ctx, reqStorage, later))
+	assert.Len(t, query(nil), 1)
+
+	assert.NoError(t, b.pruneIssuance(ctx, reqStorage, later.Add(defaultIssuanceLogRetention)))
+	assert.Empty(t, query(nil))
+}
@@ -3,6 +3,7 @@ package qdrant
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 
@@ -20,6 +21,11 @@ type JWTParameters struct {
 	DBId   string `json:"dbId"`
 	RoleId string `json:"role"`
 	Token  string `json:"token"`
+	JTI    string `json:"jti,omitempty"`
+
+	// issued is the issuance log entry of the token, if the instance
+	// keeps one
+	issued *IssuanceRecord
 }
 
 func pathJWT(b *QdrantBackend) []*framework.Path {
@@ -53,14 +59,24 @@ func pathJWT(b *QdrantBackend) []*framework.Path {
 
 func (b *QdrantBackend) pathReadJWT(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 
+	start := time.Now()
+
+	resp, err := b.readJWT(ctx, req, data)
+	recordIssuance(data.Get("dbId").(string), data.Get("role").(string), start, err)
+
+	return resp, err
+}
+
+func (b *QdrantBackend) readJWT(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
 	err := data.Validate()
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(InvalidParametersError, err)), logical.ErrInvalidRequest
+		return invalidRequestResponse(InvalidParametersError, err)
 	}
 
 	jsonString, err := json.Marshal(data.Raw)
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(DecodeFailedError, err)), logical.ErrInvalidRequest
+		return invalidRequestResponse(DecodeFailedError, err)
 	}
 	params := JWTParameters{}
 	json.Unmarshal(jsonString, &params)
@@ -69,52 +85,142 @@ func (b *QdrantBackend) pathReadJWT(ctx context.Context, req *logical.Request, d
 	config, err := readConfig(ctx, req.Storage, params.DBId)
 
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(ReadingConfigFailedError, err)), nil
+		return errorResponse(ReadingConfigFailedError, err)
 	}
 
 	if config == nil {
-		return logical.ErrorResponse(BuildErrResponse(ConfigNotFoundError, err)), nil
+		return notFoundResponse(ConfigNotFoundError)
 	}
 
 	// get role
 	role, err := readRole(ctx, req.Storage, params.DBId, params.RoleId)
 
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(ReadingRoleFailedError, err)), nil
+		return errorResponse(ReadingRoleFailedError, err)
 	}
 
 	if role == nil {
-		return logical.ErrorResponse(RoleNotFoundError), nil
+		return notFoundResponse(RoleNotFoundError)
+	}
+
+	if role.Kind == roleKindStatic && staticSource(role) == staticSourceReadOnlyKey {
+		return invalidRequestResponse(ReadingJWTFailedError, errors.New("read the credential of the role from static-creds"))
+	}
+
+	err = b.checkBindings(req, role)
+	if err != nil {
+		return errorResponse(ReadingJWTFailedError, err)
 	}
+
+	// the issuance log is written on the active node
+	if config.IssuanceLog && b.forwardWrites() {
+		return nil, logical.ErrReadOnly
+	}
+
+	now := time.Now()
+
+	limits := roleRateLimits(role, req.EntityID)
+	if len(limits) > 0 {
+		unlock, err := b.lockRateLimits(role)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+
+		err = checkRateLimits(ctx, req.Storage, limits, now)
+		if err != nil {
+			return errorResponse(ReadingJWTFailedError, err)
+		}
+	}
+
 	// Generate JWT token
-	err = b.generateJWT(config, role, &params)
+	err = b.generateJWT(ctx, req.Storage, config, role, &params)
 
 	if err != nil {
-		return logical.ErrorResponse(err.Error()), nil
+		return errorResponse(ReadingJWTFailedError, err)
+	}
+
+	if len(limits) > 0 {
+		err = recordRateLimits(ctx, req.Storage, limits, now, now.Add(tokenTTL(config, role)))
+		if err != nil {
+			return errorResponse(ReadingJWTFailedError, err)
+		}
+	}
+
+	// tokens are only handed out once they are recorded
+	if params.issued != nil {
+		params.issued.EntityID = req.EntityID
+		err = storeIssuance(ctx, req.Storage, params.DBId, params.issued)
+		if err != nil {
+			return errorResponse(ReadingJWTFailedError, err)
+		}
 	}
 
 	return createResponseJWT(&params)
 
 }
 
-func (b *QdrantBackend) generateJWT(config *ConfigParameters, role *RoleParameters, jwt_token *JWTParameters) error {
+func (b *QdrantBackend) generateJWT(ctx context.Context, storage logical.Storage, config *ConfigParameters, role *RoleParameters, jwt_token *JWTParameters) error {
+
+	// resolve templates and inherited roles
+	resolvedRole, err := resolveRole(ctx, storage, role)
+	if err != nil {
+		return err
+	}
+
+	resolved := *resolvedRole
+
+	// expand collection patterns against the live instance
+	if hasPatterns(resolved.Collections) {
+		names, err := b.listCollections(ctx, storage, config)
+		if err != nil {
+			return err
+		}
+
+		limit := config.PatternLimit
+		if limit <= 0 {
+			limit = defaultPatternLimit
+		}
+
+		resolved.Collections, err = expandCollections(resolvedRole.Collections, names, limit)
+		if err != nil {
+			return invalid(err)
+		}
+
+		// keep an explicit empty access list when nothing matches
+		if resolved.Collections == nil {
+			resolved.Collections = []CollectionAccess{}
+		}
+	}
+
+	claims, err := buildClaims(&resolved)
+	if err != nil {
+		return invalid(err)
+	}
+
+	err = enforceAccessPolicy(config.MaxAccess, claims)
+	if err != nil {
+		return invalid(err)
+	}
 
-	claims := role.Claims
+	if role.BoundCIDRsClaim != "" {
+		claims[role.BoundCIDRsClaim] = role.BoundCIDRs
+	}
 
 	claims["iss"] = role.RoleId
 
 	now := time.Now()
 
-	var delta time.Duration
+	expiry := now.Add(tokenTTL(config, role))
 
-	if role.TokenTTL != "" {
-		delta, _ = time.ParseDuration(role.TokenTTL)
-	} else {
-		delta, _ = time.ParseDuration(config.TokenTTL)
+	if config.IssuanceLog {
+		jwt_token.issued, err = newIssuanceRecord(role.RoleId, claims, now, expiry)
+		if err != nil {
+			return err
+		}
+		jwt_token.JTI = jwt_token.issued.JTI
 	}
 
-	expiry := now.Add(delta)
-
 	claims["exp"] = jwt.NumericDate(expiry.Unix())
 
 	sig, err := jose.NewSigner(
@@ -141,6 +247,29 @@ func (b *QdrantBackend) generateJWT(config *ConfigParameters, role *RoleParamete
 
 }
 
+// tokenTTL returns the lifetime of the tokens of a role
+func tokenTTL(config *ConfigParameters, role *RoleParameters) time.Duration {
+
+	var delta time.Duration
+
+	if role.TokenTTL != "" {
+		delta, _ = time.ParseDuration(role.TokenTTL)
+	} else if role.Kind == roleKindStatic {
+		// keep the previous credential valid for a rotation period
+		period, _ := time.ParseDuration(role.RotationPeriod)
+		delta = 2 * period
+	} else {
+		delta, _ = time.ParseDuration(config.TokenTTL)
+	}
+
+	// snapshot tokens are short-lived
+	if role.Kind == roleKindSnapshot && (delta <= 0 || delta > snapshotTokenTTL) {
+		delta = snapshotTokenTTL
+	}
+
+	return delta
+}
+
 func createResponseJWT(token *JWTParameters) (*logical.Response, error) {
 
 	rval := map[string]interface{}{}
@@ -165,4 +294,5 @@ Generate JWT Token
 dbId              Instance Id
 role:             Role name.
 token:            JWT Token.
+jti:              Token identifier, if the instance keeps an issuance log.
 `
@@ -11,7 +11,7 @@ import (
 
 func TestCRUDJWT(t *testing.T) {
 
-	b, reqStorage := getTestBackend(t)
+	b, reqStorage, _ := getTestBackendWithServer(t)
 
 	t.Run("Test jwt", func(t *testing.T) {
 
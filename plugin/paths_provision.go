@@ -0,0 +1,454 @@
+package qdrant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+
+	pb "github.com/qdrant/go-client/qdrant"
+)
+
+const (
+	provisionPrefix = "collection/"
+
+	autoRoleReadSuffix      = "-ro"
+	autoRoleReadWriteSuffix = "-rw"
+)
+
+// CollectionSpec declares a collection provisioned by Vault. Either
+// Size for a single unnamed vector or Vectors for named vectors must
+// be set.
+type CollectionSpec struct {
+	DBId           string                `json:"dbId"`
+	Name           string                `json:"name"`
+	Size           uint64                `json:"size,omitempty"`
+	Distance       string                `json:"distance,omitempty"`
+	OnDisk         bool                  `json:"on_disk,omitempty"`
+	Vectors        map[string]VectorSpec `json:"vectors,omitempty"`
+	PayloadIndexes map[string]string     `json:"payload_indexes,omitempty"`
+	AutoRole       bool                  `json:"auto_role"`
+	Roles          []string              `json:"roles,omitempty"`
+}
+
+// VectorSpec declares a named vector of a collection
+type VectorSpec struct {
+	Size     uint64 `json:"size"`
+	Distance string `json:"distance"`
+	OnDisk   bool   `json:"on_disk,omitempty"`
+}
+
+func pathProvision(b *QdrantBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: provisionPrefix + framework.GenericNameRegex("dbId") + "/" + framework.GenericNameRegex("name") + "$",
+			Fields: map[string]*framework.FieldSchema{
+
+				"dbId": {
+					Type:        framework.TypeString,
+					Description: "DB identifier",
+					Required:    false,
+				},
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Collection name",
+					Required:    false,
+				},
+				"size": {
+					Type:        framework.TypeInt,
+					Description: "Size of the unnamed vector",
+				},
+				"distance": {
+					Type:        framework.TypeString,
+					Description: "Distance of the unnamed vector: Cosine, Euclid, Dot or Manhattan",
+					Default:     "Cosine",
+				},
+				"on_disk": {
+					Type:        framework.TypeBool,
+					Description: "Store the unnamed vector on disk",
+				},
+				"vectors": {
+					Type:        framework.TypeMap,
+					Description: "Named vectors: name to {size, distance, on_disk}",
+				},
+				"payload_indexes": {
+					Type:        framework.TypeKVPairs,
+					Description: "Payload indexes: field to type (keyword, integer, float, geo, text, bool, datetime)",
+				},
+				"auto_role": {
+					Type:        framework.TypeBool,
+					Description: "Create '<name>-ro' and '<name>-rw' roles scoped to the collection",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.CreateOperation: &framework.PathOperation{
+					Callback: b.pathAddProvision,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathAddProvision,
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathReadProvision,
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.pathDeleteProvision,
+				},
+			},
+			HelpSynopsis:    pathProvisionHelpSyn,
+			HelpDescription: pathProvisionHelpDesc,
+		},
+	}
+
+}
+
+func (b *QdrantBackend) pathAddProvision(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	spec := CollectionSpec{
+		DBId:     data.Get("dbId").(string),
+		Name:     data.Get("name").(string),
+		Size:     uint64(data.Get("size").(int)),
+		Distance: data.Get("distance").(string),
+		OnDisk:   data.Get("on_disk").(bool),
+		AutoRole: data.Get("auto_role").(bool),
+	}
+
+	if data.Get("size").(int) < 0 {
+		return invalidRequestResponse(InvalidParametersError, errors.New("size must not be negative"))
+	}
+
+	if vectors, ok := data.GetOk("vectors"); ok {
+		jsonString, err := json.Marshal(vectors)
+		if err == nil {
+			err = json.Unmarshal(jsonString, &spec.Vectors)
+		}
+		if err != nil {
+			return invalidRequestResponse(DecodeFailedError, err)
+		}
+	}
+
+	if indexes, ok := data.GetOk("payload_indexes"); ok {
+		spec.PayloadIndexes = indexes.(map[string]string)
+	}
+
+	warnings, err := b.provisionCollection(ctx, req.Storage, &spec, req.EntityID)
+	if err != nil {
+		return errorResponse(ProvisionCollectionFailedError, err)
+	}
+
+	rval := map[string]interface{}{}
+	err = StructToMap(&spec, &rval)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{
+		Data: rval,
+	}
+	for _, w := range warnings {
+		resp.AddWarning(w)
+	}
+	return resp, nil
+}
+
+func (b *QdrantBackend) pathReadProvision(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	spec, err := readProvision(ctx, req.Storage, data.Get("dbId").(string), data.Get("name").(string))
+	if err != nil {
+		return errorResponse(ReadingCollectionFailedError, err)
+	}
+
+	if spec == nil {
+		return notFoundResponse(CollectionNotFoundError)
+	}
+
+	rval := map[string]interface{}{}
+	err = StructToMap(spec, &rval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: rval,
+	}, nil
+}
+
+func (b *QdrantBackend) pathDeleteProvision(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	err = b.deprovisionCollection(ctx, req.Storage, data.Get("dbId").(string), data.Get("name").(string), req.EntityID)
+	if err != nil {
+		return errorResponse(DeleteCollectionFailedError, err)
+	}
+	return nil, nil
+}
+
+func readProvision(ctx context.Context, storage logical.Storage, dbId string, name string) (*CollectionSpec, error) {
+	return getFromStorage[CollectionSpec](ctx, storage, provisionPrefix+dbId+"/"+name)
+}
+
+func listProvision(ctx context.Context, storage logical.Storage, dbId string) ([]string, error) {
+	return storage.List(ctx, provisionPrefix+dbId+"/")
+}
+
+// provisionCollection creates the collection and, with auto_role, a
+// read-only and a read-write role scoped to it. The collection is
+// dropped again if a role can not be written.
+func (b *QdrantBackend) provisionCollection(ctx context.Context, storage logical.Storage, spec *CollectionSpec, entityID string) ([]string, error) {
+
+	config, err := readConfig(ctx, storage, spec.DBId)
+	if err != nil {
+		return nil, err
+	}
+
+	if config == nil {
+		return nil, notFound(ConfigNotFoundError)
+	}
+
+	existing, err := readProvision(ctx, storage, spec.DBId, spec.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		return nil, conflict(fmt.Sprintf("collection %s is already provisioned", spec.Name))
+	}
+
+	err = spec.validate()
+	if err != nil {
+		return nil, invalid(err)
+	}
+
+	var roles []RoleParameters
+	if spec.AutoRole {
+		roles = autoRoles(spec)
+		for _, role := range roles {
+			existing, err := readRole(ctx, storage, spec.DBId, role.RoleId)
+			if err != nil {
+				return nil, err
+			}
+			if existing != nil {
+				return nil, conflict(fmt.Sprintf("role %q already exists", role.RoleId))
+			}
+		}
+	}
+
+	err = b.client.createCollection(ctx, storage, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	b.resetCollections(spec.DBId)
+
+	var warnings []string
+	for _, role := range roles {
+		w, err := b.addRole(ctx, storage, role, entityID)
+		if err != nil {
+			for _, name := range spec.Roles {
+				b.deleteRole(ctx, storage, spec.DBId, name, entityID)
+			}
+			b.client.dropCollection(ctx, storage, spec.DBId, spec.Name)
+			b.resetCollections(spec.DBId)
+			return nil, fmt.Errorf("role %q: %w", role.RoleId, err)
+		}
+		warnings = append(warnings, w...)
+		spec.Roles = append(spec.Roles, role.RoleId)
+	}
+
+	err = storeInStorage[CollectionSpec](ctx, storage, provisionPrefix+spec.DBId+"/"+spec.Name, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return warnings, nil
+}
+
+// deprovisionCollection deletes the roles created for a provisioned
+// collection and drops the collection
+func (b *QdrantBackend) deprovisionCollection(ctx context.Context, storage logical.Storage, dbId string, name string, entityID string) error {
+
+	spec, err := readProvision(ctx, storage, dbId, name)
+	if err != nil {
+		return err
+	}
+
+	if spec == nil {
+		return notFound("collection was not provisioned by vault")
+	}
+
+	for _, role := range spec.Roles {
+		dependents, err := roleDependents(ctx, storage, dbId, role)
+		if err != nil {
+			return err
+		}
+		if len(dependents) > 0 {
+			return conflict(fmt.Sprintf("role %q is inherited by roles: %s", role, strings.Join(dependents, ", ")))
+		}
+	}
+
+	for _, role := range spec.Roles {
+		err = b.deleteRole(ctx, storage, dbId, role, entityID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = b.client.dropCollection(ctx, storage, dbId, name)
+	if err != nil {
+		return err
+	}
+
+	b.resetCollections(dbId)
+
+	return deleteFromStorage(ctx, storage, provisionPrefix+dbId+"/"+name)
+}
+
+// autoRoles returns the read-only and read-write roles of a collection
+func autoRoles(spec *CollectionSpec) []RoleParameters {
+	return []RoleParameters{
+		{
+			DBId:        spec.DBId,
+			RoleId:      spec.Name + autoRoleReadSuffix,
+			Collections: []CollectionAccess{{Name: spec.Name, Access: accessRead}},
+		},
+		{
+			DBId:        spec.DBId,
+			RoleId:      spec.Name + autoRoleReadWriteSuffix,
+			Collections: []CollectionAccess{{Name: spec.Name, Access: accessReadWrite}},
+		},
+	}
+}
+
+func (spec *CollectionSpec) validate() error {
+
+	if spec.Name == SYS_ROLE_TABLE {
+		return fmt.Errorf("collection %s is reserved", SYS_ROLE_TABLE)
+	}
+
+	_, err := spec.vectorsConfig()
+	if err != nil {
+		return err
+	}
+
+	_, err = spec.fieldTypes()
+	return err
+}
+
+// vectorsConfig returns the Qdrant vectors config of the spec
+func (spec *CollectionSpec) vectorsConfig() (*pb.VectorsConfig, error) {
+
+	if spec.Size > 0 && len(spec.Vectors) > 0 {
+		return nil, errors.New("size and vectors are mutually exclusive")
+	}
+
+	if len(spec.Vectors) > 0 {
+		params := map[string]*pb.VectorParams{}
+		for name, v := range spec.Vectors {
+			p, err := vectorParams(v)
+			if err != nil {
+				return nil, fmt.Errorf("vector %s: %w", name, err)
+			}
+			params[name] = p
+		}
+		return &pb.VectorsConfig{Config: &pb.VectorsConfig_ParamsMap{
+			ParamsMap: &pb.VectorParamsMap{Map: params},
+		}}, nil
+	}
+
+	p, err := vectorParams(VectorSpec{Size: spec.Size, Distance: spec.Distance, OnDisk: spec.OnDisk})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.VectorsConfig{Config: &pb.VectorsConfig_Params{Params: p}}, nil
+}
+
+func vectorParams(v VectorSpec) (*pb.VectorParams, error) {
+
+	if v.Size == 0 {
+		return nil, errors.New("missing size")
+	}
+
+	distance, err := parseDistance(v.Distance)
+	if err != nil {
+		return nil, err
+	}
+
+	params := &pb.VectorParams{
+		Size:     v.Size,
+		Distance: distance,
+	}
+	if v.OnDisk {
+		onDisk := true
+		params.OnDisk = &onDisk
+	}
+	return params, nil
+}
+
+func parseDistance(name string) (pb.Distance, error) {
+
+	if name == "" {
+		return pb.Distance_Cosine, nil
+	}
+
+	for v, n := range pb.Distance_name {
+		if v != int32(pb.Distance_UnknownDistance) && strings.EqualFold(n, name) {
+			return pb.Distance(v), nil
+		}
+	}
+
+	return pb.Distance_UnknownDistance, fmt.Errorf("unknown distance %q", name)
+}
+
+// fieldTypes returns the Qdrant field types of the payload indexes
+func (spec *CollectionSpec) fieldTypes() (map[string]pb.FieldType, error) {
+
+	types := map[string]pb.FieldType{}
+	for field, name := range spec.PayloadIndexes {
+		if field == "" || name == "" {
+			return nil, fmt.Errorf("invalid payload index %q: %q", field, name)
+		}
+		v, ok := pb.FieldType_value["FieldType"+strings.ToUpper(name[:1])+strings.ToLower(name[1:])]
+		if !ok {
+			return nil, fmt.Errorf("invalid payload index %q: %q", field, name)
+		}
+		types[field] = pb.FieldType(v)
+	}
+
+	return types, nil
+}
+
+const pathProvisionHelpSyn = `
+Provision a collection.
+`
+
+const pathProvisionHelpDesc = `
+Create or drop a collection from a declarative spec. Only collections
+provisioned by Vault can be dropped.
+
+size:              Size of the unnamed vector.
+distance:          Distance of the unnamed vector (Cosine, Euclid, Dot, Manhattan).
+on_disk:           Store the unnamed vector on disk.
+vectors:           Named vectors: name to {size, distance, on_disk}.
+payload_indexes:   Payload indexes: field to type (keyword, integer, float,
+                   geo, text, bool, datetime).
+auto_role:         Create '<name>-ro' and '<name>-rw' roles scoped to the
+                   collection. They are deleted with the collection.
+`
@@ -0,0 +1,140 @@
+package qdrant
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	pb "github.com/qdrant/go-client/qdrant"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvisionValidation(t *testing.T) {
+
+	b, reqStorage := getTestBackend(t)
+
+	t.Run("Test provision validation", func(t *testing.T) {
+
+		ctx := context.Background()
+
+		write := func(data map[string]interface{}) error {
+			_, err := b.HandleRequest(ctx, &logical.Request{
+				Operation: logical.CreateOperation,
+				Path:      "collection/instance1/orders",
+				Storage:   reqStorage,
+				Data:      data,
+			})
+			return err
+		}
+
+		// instance is not configured
+		err := write(map[string]interface{}{"size": 4})
+		assertErrorCode(t, err, ErrCodeNotFound, http.StatusNotFound)
+
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "config/instance1",
+			Storage:   reqStorage,
+			Data: map[string]interface{}{
+				"url":     "localhost:6334",
+				"sig_key": "your-very-long-256-bit-secret-key",
+				"sig_alg": "HS256",
+				"jwt_ttl": "3s",
+			},
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+
+		// invalid specs are rejected before calling the instance
+		for _, data := range []map[string]interface{}{
+			{},
+			{"size": 4, "distance": "Hamming"},
+			{"size": 4, "vectors": map[string]interface{}{"text": map[string]interface{}{"size": 4}}},
+			{"size": 4, "payload_indexes": map[string]interface{}{"tenant": "uuid"}},
+		} {
+			err = write(data)
+			assertErrorCode(t, err, ErrCodeInvalidRequest, http.StatusBadRequest)
+		}
+
+		// an existing role blocks auto roles
+		err = storeInStorage(ctx, reqStorage, "role/instance1/orders-rw", &RoleParameters{DBId: "instance1", RoleId: "orders-rw"})
+		assert.NoError(t, err)
+
+		err = write(map[string]interface{}{"size": 4, "auto_role": true})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "orders-rw")
+
+		// only provisioned collections can be dropped
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.DeleteOperation,
+			Path:      "collection/instance1/orders",
+			Storage:   reqStorage,
+		})
+		assert.Error(t, err)
+		assert.Nil(t, resp)
+
+		// provisioned collections are read from storage
+		err = storeInStorage(ctx, reqStorage, "collection/instance1/users", &CollectionSpec{DBId: "instance1", Name: "users", Size: 4})
+		assert.NoError(t, err)
+
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "collection/instance1/users",
+			Storage:   reqStorage,
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+		assert.Equal(t, float64(4), resp.Data["size"])
+
+		err = write(map[string]interface{}{"size": 4})
+		assert.Error(t, err)
+
+		err = b.deleteConfig(ctx, reqStorage, ConfigParameters{DBId: "instance1"}, "")
+		assert.NoError(t, err)
+
+		spec, err := readProvision(ctx, reqStorage, "instance1", "users")
+		assert.NoError(t, err)
+		assert.Nil(t, spec)
+	})
+}
+
+func TestCollectionSpec(t *testing.T) {
+
+	spec := &CollectionSpec{
+		Name: "orders",
+		Vectors: map[string]VectorSpec{
+			"text":  {Size: 384, Distance: "cosine", OnDisk: true},
+			"image": {Size: 512, Distance: "Dot"},
+		},
+		PayloadIndexes: map[string]string{"tenant": "keyword", "created": "DateTime"},
+	}
+
+	vectors, err := spec.vectorsConfig()
+	assert.NoError(t, err)
+	params := vectors.GetParamsMap().GetMap()
+	assert.Equal(t, pb.Distance_Cosine, params["text"].Distance)
+	assert.True(t, params["text"].GetOnDisk())
+	assert.Equal(t, uint64(512), params["image"].Size)
+	assert.Nil(t, params["image"].OnDisk)
+
+	types, err := spec.fieldTypes()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]pb.FieldType{
+		"tenant":  pb.FieldType_FieldTypeKeyword,
+		"created": pb.FieldType_FieldTypeDatetime,
+	}, types)
+
+	spec = &CollectionSpec{Name: "orders", Size: 4}
+	vectors, err = spec.vectorsConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, pb.Distance_Cosine, vectors.GetParams().Distance)
+
+	assert.Error(t, (&CollectionSpec{Name: SYS_ROLE_TABLE, Size: 4}).validate())
+
+	roles := autoRoles(&CollectionSpec{DBId: "instance1", Name: "orders"})
+	assert.Equal(t, "orders-ro", roles[0].RoleId)
+	assert.Equal(t, []CollectionAccess{{Name: "orders", Access: "r"}}, roles[0].Collections)
+	assert.Equal(t, "orders-rw", roles[1].RoleId)
+	assert.Equal(t, []CollectionAccess{{Name: "orders", Access: "rw"}}, roles[1].Collections)
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -11,13 +14,39 @@ import (
 const (
 	rolePath   = "role"
 	rolePrefix = "role/"
+
+	// rolePageSize is the number of sys_roles points read per scroll
+	rolePageSize = 100
 )
 
 type RoleParameters struct {
-	DBId     string                 `json:"dbId"`
-	RoleId   string                 `json:"role"`
-	TokenTTL string                 `json:"jwt_ttl,omitempty"`
-	Claims   map[string]interface{} `json:"claims"`
+	DBId        string                 `json:"dbId"`
+	RoleId      string                 `json:"role"`
+	TokenTTL    string                 `json:"jwt_ttl,omitempty"`
+	Claims      map[string]interface{} `json:"claims"`
+	Collections []CollectionAccess     `json:"collections,omitempty"`
+
+	StrictCollections string `json:"strict_collections,omitempty"`
+
+	Template string            `json:"template,omitempty"`
+	Vars     map[string]string `json:"vars,omitempty"`
+	Inherit  []string          `json:"inherit,omitempty"`
+
+	Kind           string `json:"kind,omitempty"`
+	RotationPeriod string `json:"rotation_period,omitempty"`
+	StaticSource   string `json:"static_source,omitempty"`
+
+	RateLimit            int `json:"rate_limit,omitempty"`
+	EntityRateLimit      int `json:"entity_rate_limit,omitempty"`
+	MaxValidTokens       int `json:"max_valid_tokens,omitempty"`
+	EntityMaxValidTokens int `json:"entity_max_valid_tokens,omitempty"`
+
+	BoundCIDRs      []string `json:"bound_cidrs,omitempty"`
+	BoundEntityIDs  []string `json:"bound_entity_ids,omitempty"`
+	BoundGroupIDs   []string `json:"bound_group_ids,omitempty"`
+	BoundCIDRsClaim string   `json:"bound_cidrs_claim,omitempty"`
+
+	Generation int `json:"generation,omitempty"`
 }
 
 func pathRole(b *QdrantBackend) []*framework.Path {
@@ -43,10 +72,81 @@ func pathRole(b *QdrantBackend) []*framework.Path {
 					Required:    true,
 				},
 
+				"collections": {
+					Type:        framework.TypeSlice,
+					Description: `List of collection access entries ({name, access, payload}) merged into the 'access' claim.`,
+				},
+
+				"strict_collections": {
+					Type:        framework.TypeString,
+					Description: `Check that referenced collections exist: 'off', 'strict' or 'warn'. Defaults to the instance setting.`,
+				},
+
+				"template": {
+					Type:        framework.TypeString,
+					Description: `Name of the template providing the role claims.`,
+				},
+
+				"vars": {
+					Type:        framework.TypeKVPairs,
+					Description: `Values for the template placeholders.`,
+				},
+
+				"inherit": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: `Roles to inherit claims and access from.`,
+				},
+				"kind": {
+					Type:        framework.TypeString,
+					Description: `Role kind: empty for claims based roles, 'snapshot' for short-lived collection tokens or 'static' for static credentials.`,
+				},
+				"rotation_period": {
+					Type:        framework.TypeString,
+					Description: `Duration between rotations of a static role credential.`,
+				},
+				"static_source": {
+					Type:        framework.TypeString,
+					Description: `Static credential source: 'jwt' (default) or 'read_only_key'.`,
+				},
+
 				"jwt_ttl": {
 					Type:        framework.TypeString,
 					Description: `Duration a token is valid for (mapped to the 'exp' claim).`,
 				},
+
+				"rate_limit": {
+					Type:        framework.TypeInt,
+					Description: `Maximum number of tokens issued for the role per minute.`,
+				},
+				"entity_rate_limit": {
+					Type:        framework.TypeInt,
+					Description: `Maximum number of tokens issued for the role per minute to a single entity.`,
+				},
+				"max_valid_tokens": {
+					Type:        framework.TypeInt,
+					Description: `Maximum number of valid tokens issued for the role.`,
+				},
+				"entity_max_valid_tokens": {
+					Type:        framework.TypeInt,
+					Description: `Maximum number of valid tokens issued for the role to a single entity.`,
+				},
+
+				"bound_cidrs": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: `CIDR blocks requests for tokens of the role must come from.`,
+				},
+				"bound_entity_ids": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: `Entities allowed to request tokens of the role.`,
+				},
+				"bound_group_ids": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: `Identity groups whose members may request tokens of the role.`,
+				},
+				"bound_cidrs_claim": {
+					Type:        framework.TypeString,
+					Description: `Claim carrying the bound CIDRs in the role's tokens, e.g. for proxies.`,
+				},
 			},
 			Operations: map[logical.Operation]framework.OperationHandler{
 				logical.CreateOperation: &framework.PathOperation{
@@ -74,6 +174,26 @@ func pathRole(b *QdrantBackend) []*framework.Path {
 					Description: "DB identifier",
 					Required:    false,
 				},
+				"after": {
+					Type:        framework.TypeString,
+					Description: "List roles sorted after this name",
+					Query:       true,
+				},
+				"limit": {
+					Type:        framework.TypeInt,
+					Description: "Max number of roles returned (all if 0)",
+					Query:       true,
+				},
+				"collection": {
+					Type:        framework.TypeString,
+					Description: "Only list roles granting access to this collection",
+					Query:       true,
+				},
+				"access": {
+					Type:        framework.TypeString,
+					Description: "Only list roles granting this access level ('r', 'rw' or 'm')",
+					Query:       true,
+				},
 			},
 
 			Operations: map[logical.Operation]framework.OperationHandler{
@@ -91,7 +211,7 @@ func pathRole(b *QdrantBackend) []*framework.Path {
 func (b *QdrantBackend) pathAddRole(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	err := data.Validate()
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(InvalidParametersError, err)), logical.ErrInvalidRequest
+		return invalidRequestResponse(InvalidParametersError, err)
 	}
 
 	jsonString, err := json.Marshal(data.Raw)
@@ -99,15 +219,30 @@ func (b *QdrantBackend) pathAddRole(ctx context.Context, req *logical.Request, d
 	b.Logger().Debug("pathAddRole", jsonString)
 
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(DecodeFailedError, err)), logical.ErrInvalidRequest
+		return invalidRequestResponse(DecodeFailedError, err)
 	}
 	params := RoleParameters{}
 	json.Unmarshal(jsonString, &params)
+	params.RateLimit = data.Get("rate_limit").(int)
+	params.EntityRateLimit = data.Get("entity_rate_limit").(int)
+	params.MaxValidTokens = data.Get("max_valid_tokens").(int)
+	params.EntityMaxValidTokens = data.Get("entity_max_valid_tokens").(int)
+	params.BoundCIDRs = data.Get("bound_cidrs").([]string)
+	params.BoundEntityIDs = data.Get("bound_entity_ids").([]string)
+	params.BoundGroupIDs = data.Get("bound_group_ids").([]string)
 
-	err = b.addRole(ctx, req.Storage, params)
+	warnings, err := b.addRole(ctx, req.Storage, params, req.EntityID)
 
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(AddingRoleFailedError, err)), nil
+		return errorResponse(AddingRoleFailedError, err)
+	}
+
+	if len(warnings) > 0 {
+		resp := &logical.Response{}
+		for _, w := range warnings {
+			resp.AddWarning(w)
+		}
+		return resp, nil
 	}
 	return nil, nil
 }
@@ -116,12 +251,12 @@ func (b *QdrantBackend) pathReadRole(ctx context.Context, req *logical.Request,
 
 	err := data.Validate()
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(InvalidParametersError, err)), logical.ErrInvalidRequest
+		return invalidRequestResponse(InvalidParametersError, err)
 	}
 
 	jsonString, err := json.Marshal(data.Raw)
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(DecodeFailedError, err)), logical.ErrInvalidRequest
+		return invalidRequestResponse(DecodeFailedError, err)
 	}
 	params := RoleParameters{}
 	json.Unmarshal(jsonString, &params)
@@ -129,14 +264,34 @@ func (b *QdrantBackend) pathReadRole(ctx context.Context, req *logical.Request,
 	role, err := readRole(ctx, req.Storage, params.DBId, params.RoleId)
 
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(ReadingRoleFailedError, err)), nil
+		return errorResponse(ReadingRoleFailedError, err)
 	}
 
 	if role == nil {
-		return logical.ErrorResponse(RoleNotFoundError), nil
+		return notFoundResponse(RoleNotFoundError)
 	}
 
-	return createResponseRole(role)
+	resp, err := createResponseRole(role)
+	if err != nil {
+		return nil, err
+	}
+
+	// show claims resolved from templates and inherited roles
+	if role.Template != "" || len(role.Inherit) > 0 {
+		resolved, err := resolveRole(ctx, req.Storage, role)
+		if err != nil {
+			return errorResponse(ReadingRoleFailedError, err)
+		}
+
+		claims, err := buildClaims(resolved)
+		if err != nil {
+			return errorResponse(ReadingRoleFailedError, err)
+		}
+
+		resp.Data["resolved_claims"] = claims
+	}
+
+	return resp, nil
 
 }
 
@@ -144,50 +299,182 @@ func (b *QdrantBackend) pathListRole(ctx context.Context, req *logical.Request,
 
 	err := data.Validate()
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(InvalidParametersError, err)), logical.ErrInvalidRequest
+		return invalidRequestResponse(InvalidParametersError, err)
 	}
 
 	jsonString, err := json.Marshal(data.Raw)
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(DecodeFailedError, err)), logical.ErrInvalidRequest
+		return invalidRequestResponse(DecodeFailedError, err)
 	}
 	params := RoleParameters{}
 	json.Unmarshal(jsonString, &params)
 
 	b.Logger().Debug("list role path", rolePrefix+params.DBId)
 
-	entries, err := listRole(ctx, req.Storage, params.DBId)
+	limit := data.Get("limit").(int)
+	if limit < 0 {
+		return invalidRequestResponse(InvalidParametersError, errors.New("limit must not be negative"))
+	}
+
+	filter := roleFilter{
+		Collection: data.Get("collection").(string),
+		Access:     data.Get("access").(string),
+	}
+
+	switch filter.Access {
+	case "", accessRead, accessReadWrite, accessManage:
+	default:
+		return invalidRequestResponse(InvalidParametersError, fmt.Errorf("unknown access %s", filter.Access))
+	}
+
+	entries, err := listRolePage(ctx, req.Storage, params.DBId, data.Get("after").(string), limit, filter)
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(ListRoleFailedError, err)), nil
+		return errorResponse(ListRoleFailedError, err)
 	}
 
-	return logical.ListResponse(entries), nil
+	keyInfo := map[string]interface{}{}
+	for _, name := range entries {
+		role, err := readRole(ctx, req.Storage, params.DBId, name)
+		if err != nil {
+			return errorResponse(ListRoleFailedError, err)
+		}
+		if role == nil {
+			continue
+		}
+
+		sync, err := readRoleSync(ctx, req.Storage, params.DBId, name)
+		if err != nil {
+			return errorResponse(ListRoleFailedError, err)
+		}
+
+		keyInfo[name] = roleKeyInfo(ctx, req.Storage, role, sync)
+	}
+
+	return logical.ListResponseWithInfo(entries, keyInfo), nil
+}
+
+// roleKeyInfo summarises a role for list responses
+func roleKeyInfo(ctx context.Context, storage logical.Storage, role *RoleParameters, sync *RoleSyncState) map[string]interface{} {
+
+	info := map[string]interface{}{
+		"jwt_ttl":    role.TokenTTL,
+		"generation": role.Generation,
+		"access":     accessSummary(ctx, storage, role),
+		"sync_state": "",
+	}
+
+	if sync != nil {
+		info["sync_state"] = sync.State
+		if sync.LastSynced != nil {
+			info["last_synced"] = sync.LastSynced
+		}
+		if sync.LastError != "" {
+			info["last_error"] = sync.LastError
+		}
+	}
+
+	return info
+}
+
+// resolvedAccess returns the claims of a role built from its exact
+// collections and, separately, its collection patterns which are only
+// expanded at issue time
+func resolvedAccess(ctx context.Context, storage logical.Storage, role *RoleParameters) (map[string]interface{}, []CollectionAccess, error) {
+
+	resolved, err := resolveRole(ctx, storage, role)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exact := *resolved
+	if resolved.Collections != nil {
+		exact.Collections = append([]CollectionAccess{}, exactCollections(resolved.Collections)...)
+	}
+
+	claims, err := buildClaims(&exact)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var patterns []CollectionAccess
+	for _, c := range resolved.Collections {
+		if c.isPattern() {
+			patterns = append(patterns, c)
+		}
+	}
+
+	return claims, patterns, nil
+}
+
+// accessSummary returns the resolved access of a role, e.g. 'users:r, orders:rw'
+func accessSummary(ctx context.Context, storage logical.Storage, role *RoleParameters) string {
+
+	claims, patterns, err := resolvedAccess(ctx, storage, role)
+	if err != nil {
+		return "unresolved: " + err.Error()
+	}
+
+	switch access := claims[accessClaim].(type) {
+	case nil:
+		return ""
+	case string:
+		return "global:" + access
+	case []interface{}:
+		var entries []string
+		for _, v := range access {
+			entry, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			summary := fmt.Sprintf("%v:%v", entry["collection"], entry["access"])
+			if _, ok := entry["payload"]; ok {
+				summary += "(payload)"
+			}
+			entries = append(entries, summary)
+		}
+		for _, c := range patterns {
+			entries = append(entries, fmt.Sprintf("%s:%s(%s)", c.Name, c.Access, c.Match))
+		}
+		return strings.Join(entries, ", ")
+	default:
+		return ""
+	}
 }
 
 func (b *QdrantBackend) pathDeleteRole(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 
 	err := data.Validate()
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(InvalidParametersError, err)), logical.ErrInvalidRequest
+		return invalidRequestResponse(InvalidParametersError, err)
 	}
 
 	jsonString, err := json.Marshal(data.Raw)
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(DecodeFailedError, err)), logical.ErrInvalidRequest
+		return invalidRequestResponse(DecodeFailedError, err)
 	}
 	params := RoleParameters{}
 	json.Unmarshal(jsonString, &params)
 
+	// roles inherited by other roles can not be deleted
+	dependents, err := roleDependents(ctx, req.Storage, params.DBId, params.RoleId)
+	if err != nil {
+		return errorResponse(DeleteRoleFailedError, err)
+	}
+
+	if len(dependents) > 0 {
+		return conflictResponse(DeleteRoleFailedError, errors.New("role is inherited by roles: "+strings.Join(dependents, ", ")))
+	}
+
 	// delete role
-	err = b.deleteRole(ctx, req.Storage, params.DBId, params.RoleId)
+	err = b.deleteRole(ctx, req.Storage, params.DBId, params.RoleId, req.EntityID)
 	if err != nil {
-		return logical.ErrorResponse(BuildErrResponse(DeleteRoleFailedError, err)), logical.ErrInvalidRequest
+		return errorResponse(DeleteRoleFailedError, err)
 	}
 	return nil, nil
 
 }
 
-func (b *QdrantBackend) addRole(ctx context.Context, storage logical.Storage, params RoleParameters) error {
+func (b *QdrantBackend) addRole(ctx context.Context, storage logical.Storage, params RoleParameters, entityID string) ([]string, error) {
 
 	path := rolePrefix + params.DBId + "/" + params.RoleId
 
@@ -196,27 +483,135 @@ func (b *QdrantBackend) addRole(ctx context.Context, storage logical.Storage, pa
 	config, err := readConfig(ctx, storage, params.DBId)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if config == nil {
-		return errors.New(ConfigNotFoundError)
+		return nil, notFound(ConfigNotFoundError)
+	}
+
+	warnings, err := b.checkRole(ctx, storage, config, &params)
+	if err != nil {
+		return nil, err
 	}
 
 	//store role in database
-	err = b.client.createRole(ctx, storage, &params)
+	err = b.registry.PutRoles(ctx, storage, params.DBId, []string{params.RoleId})
 	if err != nil {
-		return err
+		return nil, b.recordFailedSync(ctx, storage, params.DBId, params.RoleId, err)
+	}
+
+	err = recordRoleSync(ctx, storage, params.DBId, []string{params.RoleId}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// keep previous versions of the role
+	err = recordRoleVersion(ctx, storage, config, &params, entityID)
+	if err != nil {
+		return nil, err
 	}
 
 	err = storeInStorage[RoleParameters](ctx, storage, path, &params)
 
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	return warnings, nil
+
+}
+
+// recordFailedSync records a failed push of a stored role and returns
+// the push error. A new role is not stored when its push fails, so it
+// has no sync state to record.
+func (b *QdrantBackend) recordFailedSync(ctx context.Context, storage logical.Storage, dbId string, name string, syncErr error) error {
+
+	current, err := readRole(ctx, storage, dbId, name)
+	if err == nil && current != nil {
+		err = recordRoleSync(ctx, storage, dbId, []string{name}, syncErr)
+	}
+	if err != nil {
+		b.Logger().Error("recording failed sync failed", "instance", dbId, "role", name, "error", err)
+	}
+
+	return syncErr
+}
+
+// checkRole validates the role against the instance before it is
+// stored. Missing collections in warn mode are returned as warnings.
+func (b *QdrantBackend) checkRole(ctx context.Context, storage logical.Storage, config *ConfigParameters, params *RoleParameters) ([]string, error) {
+
+	err := validateStrictCollections(params.StrictCollections)
+	if err != nil {
+		return nil, invalid(err)
+	}
+
+	err = validateRoleKind(params)
+	if err != nil {
+		return nil, invalid(err)
+	}
+
+	err = validateRateLimits(params)
+	if err != nil {
+		return nil, invalid(err)
+	}
+
+	err = validateBindings(params)
+	if err != nil {
+		return nil, invalid(err)
 	}
 
-	return nil
+	resolved, err := resolveRole(ctx, storage, params)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateResolvedRole(resolved)
+	if err != nil {
+		return nil, invalid(err)
+	}
+
+	// check the instance access ceiling, patterns are checked on issuance
+	exact := *resolved
+	if resolved.Collections != nil {
+		exact.Collections = append([]CollectionAccess{}, exactCollections(resolved.Collections)...)
+	}
+
+	claims, err := buildClaims(&exact)
+	if err != nil {
+		return nil, invalid(err)
+	}
+
+	if isReadOnlyKeyRole(resolved) {
+		claims = readOnlyKeyClaims()
+	}
+
+	err = enforceAccessPolicy(config.MaxAccess, claims)
+	if err != nil {
+		return nil, invalid(err)
+	}
+
+	// check referenced collections exist
+	var warnings []string
+
+	mode := strictCollectionsMode(config, params)
+	referenced := referencedCollections(resolved)
+	if mode != strictOff && len(referenced) > 0 {
+		missing, err := b.client.missingCollections(ctx, storage, params.DBId, referenced)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(missing) > 0 {
+			if mode == strictStrict {
+				return nil, invalid(errors.New(missingCollectionsMessage(missing)))
+			}
+			warnings = append(warnings, missingCollectionsMessage(missing))
+		}
+	}
 
+	return warnings, nil
 }
 
 func readRole(ctx context.Context, storage logical.Storage, dbId string, role string) (*RoleParameters, error) {
@@ -225,6 +620,172 @@ func readRole(ctx context.Context, storage logical.Storage, dbId string, role st
 	return getFromStorage[RoleParameters](ctx, storage, path)
 }
 
+// readAllRoles returns every role of the instance
+func readAllRoles(ctx context.Context, storage logical.Storage, dbId string) ([]*RoleParameters, error) {
+
+	entries, err := listRole(ctx, storage, dbId)
+	if err != nil {
+		return nil, err
+	}
+
+	var roles []*RoleParameters
+	for _, name := range entries {
+		role, err := readRole(ctx, storage, dbId, name)
+		if err != nil {
+			return nil, err
+		}
+		if role != nil {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+// roleDependents returns the roles inheriting from the role
+func roleDependents(ctx context.Context, storage logical.Storage, dbId string, name string) ([]string, error) {
+
+	roles, err := readAllRoles(ctx, storage, dbId)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, role := range roles {
+		for _, parent := range role.Inherit {
+			if parent == name {
+				names = append(names, role.RoleId)
+				break
+			}
+		}
+	}
+	return names, nil
+}
+
+// resolveRole returns a copy of the role with claims and collections
+// merged from inherited roles, the template and the role itself
+func resolveRole(ctx context.Context, storage logical.Storage, role *RoleParameters) (*RoleParameters, error) {
+	return resolveRoleWith(ctx, storage, role, nil)
+}
+
+// resolveRoleWith resolves the role using override in place of the
+// stored template of the same name
+func resolveRoleWith(ctx context.Context, storage logical.Storage, role *RoleParameters, override *TemplateParameters) (*RoleParameters, error) {
+	return resolveRoleChain(ctx, storage, role, override, map[string]bool{})
+}
+
+func resolveRoleChain(ctx context.Context, storage logical.Storage, role *RoleParameters, override *TemplateParameters, visiting map[string]bool) (*RoleParameters, error) {
+
+	if role.Template == "" && len(role.Inherit) == 0 {
+		return role, nil
+	}
+
+	if visiting[role.RoleId] {
+		return nil, invalid(fmt.Errorf("inheritance cycle at role %q", role.RoleId))
+	}
+	visiting[role.RoleId] = true
+	defer delete(visiting, role.RoleId)
+
+	claims := map[string]interface{}{}
+	var collections []CollectionAccess
+
+	for _, name := range role.Inherit {
+		if visiting[name] {
+			return nil, invalid(fmt.Errorf("inheritance cycle at role %q", name))
+		}
+
+		parent, err := readRole(ctx, storage, role.DBId, name)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil {
+			return nil, invalid(fmt.Errorf("inherited role %q not found", name))
+		}
+
+		if parent.Kind == roleKindSnapshot {
+			return nil, invalid(fmt.Errorf("inherited role %q is a snapshot role", name))
+		}
+
+		parent, err = resolveRoleChain(ctx, storage, parent, override, visiting)
+		if err != nil {
+			return nil, err
+		}
+
+		claims, err = mergeClaims(claims, parent.Claims)
+		if err != nil {
+			return nil, invalid(fmt.Errorf("inherited role %q: %w", name, err))
+		}
+		collections = mergeCollectionList(collections, parent.Collections)
+	}
+
+	if role.Template != "" {
+		template := override
+		if template == nil || template.Name != role.Template {
+			var err error
+			template, err = readTemplate(ctx, storage, role.DBId, role.Template)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if template == nil {
+			return nil, invalid(fmt.Errorf("template %q not found", role.Template))
+		}
+
+		templateClaims, templateCollections, err := renderTemplate(template, role.Vars)
+		if err != nil {
+			return nil, invalid(fmt.Errorf("template %q: %w", role.Template, err))
+		}
+
+		claims, err = mergeClaims(claims, templateClaims)
+		if err != nil {
+			return nil, invalid(fmt.Errorf("template %q: %w", role.Template, err))
+		}
+		collections = mergeCollectionList(collections, templateCollections)
+	}
+
+	claims, err := mergeClaims(claims, role.Claims)
+	if err != nil {
+		return nil, invalid(err)
+	}
+	collections = mergeCollectionList(collections, role.Collections)
+
+	resolved := *role
+	resolved.Claims = claims
+	resolved.Collections = collections
+	if len(collections) == 0 {
+		resolved.Collections = nil
+	}
+
+	return &resolved, nil
+}
+
+// validateRoleKind checks the role follows the rules of its kind and
+// fills in the defaults of the kind
+func validateRoleKind(role *RoleParameters) error {
+
+	switch role.Kind {
+	case "":
+		return nil
+	case roleKindSnapshot:
+		return validateSnapshotRole(role)
+	case roleKindStatic:
+		return validateStaticRole(role)
+	default:
+		return fmt.Errorf("unknown kind %q", role.Kind)
+	}
+}
+
+// validateResolvedRole checks the resolved role can be signed
+func validateResolvedRole(resolved *RoleParameters) error {
+
+	err := validateCollections(resolved.Collections)
+	if err != nil {
+		return err
+	}
+
+	_, err = buildClaims(resolved)
+	return err
+}
+
 func listRole(ctx context.Context, storage logical.Storage, dbId string) ([]string, error) {
 
 	path := rolePrefix + dbId + "/"
@@ -241,7 +802,66 @@ func listRole(ctx context.Context, storage logical.Storage, dbId string) ([]stri
 	return roles, nil
 }
 
-func (b *QdrantBackend) deleteRole(ctx context.Context, storage logical.Storage, dbId string, name string) error {
+// roleFilter selects roles by the access they grant
+type roleFilter struct {
+	Collection string
+	Access     string
+}
+
+func (f roleFilter) empty() bool {
+	return f.Collection == "" && f.Access == ""
+}
+
+// matches reports whether the resolved role grants the filtered access.
+// Roles which can not be resolved never match.
+func (f roleFilter) matches(ctx context.Context, storage logical.Storage, role *RoleParameters) bool {
+
+	claims, patterns, err := resolvedAccess(ctx, storage, role)
+	if err != nil {
+		return false
+	}
+
+	ok, err := grantsAccess(claims, patterns, f.Collection, f.Access)
+	return err == nil && ok
+}
+
+// listRolePage returns up to limit role names sorted after the given
+// name which match the filter. The role keys are listed once and roles
+// are read until the page is full.
+func listRolePage(ctx context.Context, storage logical.Storage, dbId string, after string, limit int, filter roleFilter) ([]string, error) {
+
+	path := rolePrefix + dbId + "/"
+
+	if filter.empty() {
+		return listPage(ctx, storage, path, after, limit)
+	}
+
+	names, err := listAfter(ctx, storage, path, after)
+	if err != nil {
+		return nil, err
+	}
+
+	var roles []string
+	for _, name := range names {
+		role, err := readRole(ctx, storage, dbId, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if role == nil || !filter.matches(ctx, storage, role) {
+			continue
+		}
+
+		roles = append(roles, name)
+		if limit > 0 && len(roles) == limit {
+			break
+		}
+	}
+
+	return roles, nil
+}
+
+func (b *QdrantBackend) deleteRole(ctx context.Context, storage logical.Storage, dbId string, name string, entityID string) error {
 	// get stored signing keys
 	role, err := readRole(ctx, storage, dbId, name)
 	if err != nil {
@@ -253,7 +873,34 @@ func (b *QdrantBackend) deleteRole(ctx context.Context, storage logical.Storage,
 	}
 
 	//delete role in database
-	err = b.client.deleteRole(ctx, storage, role)
+	err = b.registry.DeleteRoles(ctx, storage, role.DBId, []string{role.RoleId})
+	if err != nil {
+		return err
+	}
+
+	config, err := readConfig(ctx, storage, dbId)
+	if err != nil {
+		return err
+	}
+
+	if config != nil {
+		err = recordRoleDeletion(ctx, storage, config, dbId, name, entityID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = deleteRoleSync(ctx, storage, dbId, name)
+	if err != nil {
+		return err
+	}
+
+	err = deleteFromStorage(ctx, storage, staticCredsPrefix+dbId+"/"+name)
+	if err != nil {
+		return err
+	}
+
+	err = deleteRateLimits(ctx, storage, dbId, name)
 	if err != nil {
 		return err
 	}
@@ -286,4 +933,26 @@ Configure the roles.
 
 role:              Role name.
 claims:            JSON claims.
+collections:       List of collection access entries ({name, access, payload}).
+strict_collections: Check referenced collections exist (off, strict, warn).
+template:          Template providing the role claims.
+vars:              Values for the template placeholders.
+inherit:           Roles to inherit claims and access from.
+kind:              'snapshot' for collection tokens expiring after at most 5
+                   minutes, 'static' for static credentials. Snapshot tokens
+                   grant their collection access ('r' by default) for their
+                   whole TTL and are not limited to snapshot APIs.
+rotation_period:   Duration between rotations of a static credential.
+static_source:     Static credential source: 'jwt' or 'read_only_key'.
+rate_limit:        Tokens issued for the role per minute.
+entity_rate_limit: Tokens issued for the role per minute to one entity.
+max_valid_tokens:  Valid tokens issued for the role.
+entity_max_valid_tokens: Valid tokens issued for the role to one entity.
+bound_cidrs:       CIDR blocks token requests must come from.
+bound_entity_ids:  Entities allowed to request tokens.
+bound_group_ids:   Groups whose members may request tokens.
+bound_cidrs_claim: Claim carrying the bound CIDRs in the tokens.
+
+Listing roles accepts 'after' and 'limit' to page through the roles
+and 'collection' and 'access' to only list roles granting that access.
 `
@@ -3,6 +3,8 @@ package qdrant
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"net/http"
 	"testing"
 
 	"github.com/hashicorp/vault/sdk/logical"
@@ -11,7 +13,7 @@ import (
 
 func TestCRUDRole(t *testing.T) {
 
-	b, reqStorage := getTestBackend(t)
+	b, reqStorage, _ := getTestBackendWithServer(t)
 
 	t.Run("Test roles", func(t *testing.T) {
 
@@ -98,9 +100,12 @@ func TestCRUDRole(t *testing.T) {
 		//t.Log(err, resp.Data)
 		assert.NoError(t, err)
 		assert.False(t, resp.IsError())
-		assert.Equal(t, resp.Data, map[string]interface{}{
-			"keys": []string{"read", "write"},
-		})
+		assert.Equal(t, []string{"read", "write"}, resp.Data["keys"])
+
+		info := resp.Data["key_info"].(map[string]interface{})["read"].(map[string]interface{})
+		assert.Equal(t, 1, info["generation"])
+		assert.Equal(t, "synced", info["sync_state"])
+		assert.NotNil(t, info["last_synced"])
 
 		// create role for non existing instance
 		json.Unmarshal([]byte(claimsRole1), &claims)
@@ -112,8 +117,8 @@ func TestCRUDRole(t *testing.T) {
 			Data:      claims,
 		})
 		//t.Log(err, resp)
-		assert.NoError(t, err)
-		assert.True(t, resp.IsError())
+		assert.Nil(t, resp)
+		assertErrorCode(t, err, ErrCodeNotFound, http.StatusNotFound)
 
 		// call read
 		resp, err = b.HandleRequest(context.Background(), &logical.Request{
@@ -126,9 +131,10 @@ func TestCRUDRole(t *testing.T) {
 		json.Unmarshal([]byte(claimsRole1), &claims)
 
 		expected = RoleParameters{
-			DBId:   "instance1",
-			RoleId: "write",
-			Claims: claims["claims"].(map[string]interface{}),
+			DBId:       "instance1",
+			RoleId:     "write",
+			Claims:     claims["claims"].(map[string]interface{}),
+			Generation: 1,
 		}
 
 		assert.NoError(t, err)
@@ -156,7 +162,7 @@ func TestCRUDRole(t *testing.T) {
 
 		assert.NoError(t, err)
 		assert.False(t, resp.IsError())
-		assert.Equal(t, resp.Data, map[string]interface{}{"keys": []string{"read"}})
+		assert.Equal(t, []string{"read"}, resp.Data["keys"])
 
 		// delete instance
 		// call delete
@@ -183,3 +189,208 @@ func TestCRUDRole(t *testing.T) {
 
 	})
 }
+
+func TestRoleMaxAccess(t *testing.T) {
+
+	b, reqStorage := getTestBackend(t)
+
+	t.Run("Test role access ceiling", func(t *testing.T) {
+
+		// create config with ceiling
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "config/instance1",
+			Storage:   reqStorage,
+			Data: map[string]interface{}{
+				"url":     "localhost:6334",
+				"sig_key": "your-very-long-256-bit-secret-key",
+				"sig_alg": "HS256",
+				"jwt_ttl": "3s",
+				"max_access": map[string]interface{}{
+					"global_levels": []interface{}{"r"},
+					"collections":   []interface{}{"events_*"},
+				},
+			},
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+
+		// manage role is rejected
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "role/instance1/admin",
+			Storage:   reqStorage,
+			Data: map[string]interface{}{
+				"claims": map[string]interface{}{"access": "m"},
+			},
+		})
+		assert.Error(t, err)
+		assert.Nil(t, resp)
+
+		// a role without access claim has manage access
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "role/instance1/empty",
+			Storage:   reqStorage,
+			Data: map[string]interface{}{
+				"claims": map[string]interface{}{},
+			},
+		})
+		assert.Error(t, err)
+		assert.Nil(t, resp)
+
+		// the read-only key grants global read access
+		config, err := readConfig(context.Background(), reqStorage, "instance1")
+		assert.NoError(t, err)
+
+		_, err = b.checkRole(context.Background(), reqStorage, config, &RoleParameters{
+			DBId:           "instance1",
+			RoleId:         "viewer",
+			Kind:           roleKindStatic,
+			RotationPeriod: "24h",
+			StaticSource:   staticSourceReadOnlyKey,
+		})
+		assert.NoError(t, err)
+
+		// collection outside of the ceiling is rejected
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "role/instance1/orders",
+			Storage:   reqStorage,
+			Data: map[string]interface{}{
+				"collections": []interface{}{
+					map[string]interface{}{"name": "orders", "access": "rw"},
+				},
+			},
+		})
+		assert.Error(t, err)
+		assert.Nil(t, resp)
+
+		// roles written before the ceiling can not issue tokens
+		err = storeInStorage(context.Background(), reqStorage, "role/instance1/admin", &RoleParameters{
+			DBId:   "instance1",
+			RoleId: "admin",
+			Claims: map[string]interface{}{"access": "m"},
+		})
+		assert.NoError(t, err)
+
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "jwt/instance1/admin",
+			Storage:   reqStorage,
+		})
+		assert.Error(t, err)
+		assert.Nil(t, resp)
+	})
+}
+
+func TestRoleKeyInfo(t *testing.T) {
+
+	_, reqStorage := getTestBackend(t)
+	ctx := context.Background()
+
+	role := &RoleParameters{
+		DBId:       "instance1",
+		RoleId:     "reader",
+		TokenTTL:   "10s",
+		Claims:     map[string]interface{}{"access": "r"},
+		Generation: 2,
+	}
+
+	info := roleKeyInfo(ctx, reqStorage, role, nil)
+	assert.Equal(t, "global:r", info["access"])
+	assert.Equal(t, 2, info["generation"])
+	assert.Equal(t, "", info["sync_state"])
+
+	role.Claims = nil
+	role.Collections = []CollectionAccess{
+		{Name: "users", Access: "r"},
+		{Name: "orders_*", Access: "rw", Match: matchGlob},
+	}
+
+	err := recordRoleSync(ctx, reqStorage, "instance1", []string{"reader"}, errors.New("unavailable"))
+	assert.NoError(t, err)
+
+	sync, err := readRoleSync(ctx, reqStorage, "instance1", "reader")
+	assert.NoError(t, err)
+
+	info = roleKeyInfo(ctx, reqStorage, role, sync)
+	assert.Equal(t, "users:r, orders_*:rw(glob)", info["access"])
+	assert.Equal(t, "failed", info["sync_state"])
+	assert.Equal(t, "unavailable", info["last_error"])
+
+	state, err := readInstanceState(ctx, reqStorage, "instance1")
+	assert.NoError(t, err)
+	assert.Nil(t, state)
+}
+
+// countingStorage counts the List calls of a storage
+type countingStorage struct {
+	logical.Storage
+	lists int
+}
+
+func (s *countingStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	s.lists++
+	return s.Storage.List(ctx, prefix)
+}
+
+func TestListRolePage(t *testing.T) {
+
+	b, reqStorage := getTestBackend(t)
+	ctx := context.Background()
+
+	roles := []*RoleParameters{
+		{DBId: "instance1", RoleId: "admin", Claims: map[string]interface{}{"access": "m"}},
+		{DBId: "instance1", RoleId: "events", Collections: []CollectionAccess{{Name: "events_*", Access: "rw", Match: matchGlob}}},
+		{DBId: "instance1", RoleId: "full", Claims: map[string]interface{}{"value_exists": true}},
+		{DBId: "instance1", RoleId: "orders", Collections: []CollectionAccess{{Name: "orders", Access: "rw"}}},
+		{DBId: "instance1", RoleId: "users", Collections: []CollectionAccess{{Name: "users", Access: "r"}, {Name: "orders", Access: "r"}}},
+	}
+
+	for _, role := range roles {
+		err := storeInStorage[RoleParameters](ctx, reqStorage, rolePrefix+role.DBId+"/"+role.RoleId, role)
+		assert.NoError(t, err)
+	}
+
+	list := func(data map[string]interface{}) []string {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      "role/instance1",
+			Storage:   reqStorage,
+			Data:      data,
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+		keys, _ := resp.Data["keys"].([]string)
+		return keys
+	}
+
+	assert.Equal(t, []string{"admin", "events"}, list(map[string]interface{}{"limit": 2}))
+	assert.Equal(t, []string{"full", "orders"}, list(map[string]interface{}{"after": "events", "limit": 2}))
+	assert.Equal(t, []string(nil), list(map[string]interface{}{"after": "users"}))
+
+	// levels include lower levels, roles without access claim have manage access
+	assert.Equal(t, []string{"admin", "full", "orders"}, list(map[string]interface{}{"collection": "orders", "access": "rw"}))
+	assert.Equal(t, []string{"admin", "full", "orders", "users"}, list(map[string]interface{}{"collection": "orders", "access": "r"}))
+	assert.Equal(t, []string{"admin", "full", "orders", "users"}, list(map[string]interface{}{"collection": "orders"}))
+	assert.Equal(t, []string{"admin", "events", "full"}, list(map[string]interface{}{"collection": "events_2026", "access": "rw"}))
+	assert.Equal(t, []string{"admin", "full"}, list(map[string]interface{}{"collection": "orders", "access": "m"}))
+	assert.Equal(t, []string{"events"}, list(map[string]interface{}{"access": "rw", "after": "admin", "limit": 1}))
+
+	// filtered pages list the role keys once
+	counting := &countingStorage{Storage: reqStorage}
+	names, err := listRolePage(ctx, counting, "instance1", "", 0, roleFilter{Access: accessReadWrite})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"admin", "events", "full", "orders"}, names)
+	assert.Equal(t, 1, counting.lists)
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "role/instance1",
+		Storage:   reqStorage,
+		Data:      map[string]interface{}{"access": "x"},
+	})
+	assert.Error(t, err)
+	assert.Nil(t, resp)
+}
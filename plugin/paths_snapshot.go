@@ -0,0 +1,272 @@
+package qdrant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	snapshotPrefix = "snapshot/"
+
+	// roleKindSnapshot roles issue short-lived collection tokens for
+	// snapshot jobs. Qdrant has no snapshot-only access, the tokens can
+	// also use the points of their collections.
+	roleKindSnapshot = "snapshot"
+
+	snapshotTokenTTL = 5 * time.Minute
+	snapshotTimeout  = 10 * time.Minute
+
+	defaultMaxSnapshots = 50
+)
+
+// SnapshotRecord is a snapshot created through Vault
+type SnapshotRecord struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Size      int64     `json:"size"`
+	Checksum  string    `json:"checksum,omitempty"`
+	EntityID  string    `json:"entity_id,omitempty"`
+}
+
+// SnapshotHistory holds the latest snapshots of a collection, oldest first
+type SnapshotHistory struct {
+	Snapshots []SnapshotRecord `json:"snapshots"`
+}
+
+func pathSnapshot(b *QdrantBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: snapshotPrefix + framework.GenericNameRegex("dbId") + "/" + framework.GenericNameRegex("collection") + "$",
+			Fields: map[string]*framework.FieldSchema{
+
+				"dbId": {
+					Type:        framework.TypeString,
+					Description: "DB identifier",
+					Required:    false,
+				},
+				"collection": {
+					Type:        framework.TypeString,
+					Description: "Collection name",
+					Required:    false,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathCreateSnapshot,
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathReadSnapshots,
+				},
+			},
+			HelpSynopsis:    pathSnapshotHelpSyn,
+			HelpDescription: pathSnapshotHelpDesc,
+		},
+		{
+			Pattern: snapshotPrefix + framework.GenericNameRegex("dbId") + "/?$",
+			Fields: map[string]*framework.FieldSchema{
+
+				"dbId": {
+					Type:        framework.TypeString,
+					Description: "DB identifier",
+					Required:    false,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.pathListSnapshots,
+				},
+			},
+			HelpSynopsis:    pathSnapshotHelpSyn,
+			HelpDescription: pathSnapshotHelpDesc,
+		},
+	}
+
+}
+
+func (b *QdrantBackend) pathCreateSnapshot(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	record, err := b.createSnapshot(ctx, req.Storage, data.Get("dbId").(string), data.Get("collection").(string), req.EntityID)
+	if err != nil {
+		return errorResponse(CreateSnapshotFailedError, err)
+	}
+
+	rval := map[string]interface{}{}
+	err = StructToMap(record, &rval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: rval,
+	}, nil
+}
+
+func (b *QdrantBackend) pathReadSnapshots(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	history, err := readSnapshotHistory(ctx, req.Storage, data.Get("dbId").(string), data.Get("collection").(string))
+	if err != nil {
+		return errorResponse(ReadingSnapshotsFailedError, err)
+	}
+
+	if history == nil {
+		return notFoundResponse(SnapshotNotFoundError)
+	}
+
+	rval := map[string]interface{}{}
+	err = StructToMap(history, &rval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: rval,
+	}, nil
+}
+
+func (b *QdrantBackend) pathListSnapshots(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	dbId := data.Get("dbId").(string)
+
+	entries, err := req.Storage.List(ctx, snapshotPrefix+dbId+"/")
+	if err != nil {
+		return errorResponse(ReadingSnapshotsFailedError, err)
+	}
+
+	keyInfo := map[string]interface{}{}
+	for _, name := range entries {
+		history, err := readSnapshotHistory(ctx, req.Storage, dbId, name)
+		if err != nil {
+			return errorResponse(ReadingSnapshotsFailedError, err)
+		}
+		if history == nil || len(history.Snapshots) == 0 {
+			continue
+		}
+
+		last := history.Snapshots[len(history.Snapshots)-1]
+		keyInfo[name] = map[string]interface{}{
+			"snapshots":     len(history.Snapshots),
+			"last_snapshot": last.Name,
+			"last_created":  last.CreatedAt,
+		}
+	}
+
+	return logical.ListResponseWithInfo(entries, keyInfo), nil
+}
+
+func readSnapshotHistory(ctx context.Context, storage logical.Storage, dbId string, collection string) (*SnapshotHistory, error) {
+	return getFromStorage[SnapshotHistory](ctx, storage, snapshotPrefix+dbId+"/"+collection)
+}
+
+// createSnapshot creates a snapshot of the collection through the
+// instance connection and records it
+func (b *QdrantBackend) createSnapshot(ctx context.Context, storage logical.Storage, dbId string, collection string, entityID string) (*SnapshotRecord, error) {
+
+	config, err := readConfig(ctx, storage, dbId)
+	if err != nil {
+		return nil, err
+	}
+
+	if config == nil {
+		return nil, notFound(ConfigNotFoundError)
+	}
+
+	if collection == SYS_ROLE_TABLE {
+		return nil, invalid(fmt.Errorf("collection %s is reserved", SYS_ROLE_TABLE))
+	}
+
+	record, err := b.client.createSnapshot(ctx, storage, dbId, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	record.EntityID = entityID
+
+	err = recordSnapshot(ctx, storage, dbId, collection, record)
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// recordSnapshot appends the snapshot to the collection's history,
+// keeping the latest defaultMaxSnapshots
+func recordSnapshot(ctx context.Context, storage logical.Storage, dbId string, collection string, record *SnapshotRecord) error {
+
+	history, err := readSnapshotHistory(ctx, storage, dbId, collection)
+	if err != nil {
+		return err
+	}
+
+	if history == nil {
+		history = &SnapshotHistory{}
+	}
+
+	history.Snapshots = append(history.Snapshots, *record)
+	if len(history.Snapshots) > defaultMaxSnapshots {
+		history.Snapshots = history.Snapshots[len(history.Snapshots)-defaultMaxSnapshots:]
+	}
+
+	return storeInStorage[SnapshotHistory](ctx, storage, snapshotPrefix+dbId+"/"+collection, history)
+}
+
+// validateSnapshotRole checks a snapshot role lists exact collections
+// only and does not carry claims, templates or inherited roles
+func validateSnapshotRole(role *RoleParameters) error {
+
+	if len(role.Claims) > 0 || role.Template != "" || len(role.Inherit) > 0 {
+		return errors.New("snapshot roles can not have claims, a template or inherited roles")
+	}
+
+	if len(role.Collections) == 0 {
+		return errors.New("snapshot roles must list collections")
+	}
+
+	for i, c := range role.Collections {
+		if c.isPattern() || c.Payload != nil {
+			return fmt.Errorf("snapshot role collection %q must be an exact name without payload", c.Name)
+		}
+
+		switch c.Access {
+		case "":
+			// rw, which creating snapshots needs, must be asked for
+			role.Collections[i].Access = accessRead
+		case accessRead, accessReadWrite:
+		default:
+			return fmt.Errorf("snapshot role collection %q: access must be 'r' or 'rw'", c.Name)
+		}
+	}
+
+	return nil
+}
+
+const pathSnapshotHelpSyn = `
+Create and audit collection snapshots.
+`
+
+const pathSnapshotHelpDesc = `
+Writing snapshot/<instance>/<collection> creates a snapshot of the
+collection through the instance connection and records its name, time,
+size and the requesting entity. Reading it returns the recorded
+snapshots of the collection, the latest 50 are kept.
+`
@@ -0,0 +1,146 @@
+package qdrant
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotRole(t *testing.T) {
+
+	b, reqStorage := getTestBackend(t)
+
+	t.Run("Test snapshot role tokens", func(t *testing.T) {
+
+		ctx := context.Background()
+
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "config/instance1",
+			Storage:   reqStorage,
+			Data: map[string]interface{}{
+				"url":     "localhost:6334",
+				"sig_key": "your-very-long-256-bit-secret-key",
+				"sig_alg": "HS256",
+				"jwt_ttl": "1h",
+			},
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+
+		role := &RoleParameters{
+			DBId:        "instance1",
+			RoleId:      "backup",
+			Kind:        roleKindSnapshot,
+			Collections: []CollectionAccess{{Name: "orders", Access: "rw"}},
+		}
+		assert.NoError(t, validateRoleKind(role))
+
+		err = storeInStorage(ctx, reqStorage, "role/instance1/backup", role)
+		assert.NoError(t, err)
+
+		err = storeInStorage(ctx, reqStorage, "role/instance1/reader", &RoleParameters{DBId: "instance1", RoleId: "reader", Inherit: []string{"backup"}})
+		assert.NoError(t, err)
+
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "jwt/instance1/backup",
+			Storage:   reqStorage,
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+
+		token, err := jwt.ParseSigned(resp.Data["token"].(string), []jose.SignatureAlgorithm{jose.HS256})
+		assert.NoError(t, err)
+
+		claims := map[string]interface{}{}
+		err = token.Claims([]byte("your-very-long-256-bit-secret-key"), &claims)
+		assert.NoError(t, err)
+
+		assert.Equal(t, []interface{}{map[string]interface{}{"collection": "orders", "access": "rw"}}, claims["access"])
+		exp := time.Unix(int64(claims["exp"].(float64)), 0)
+		assert.True(t, time.Until(exp) <= snapshotTokenTTL)
+
+		// snapshot roles can not be inherited
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "jwt/instance1/reader",
+			Storage:   reqStorage,
+		})
+		assert.Error(t, err)
+		assert.Nil(t, resp)
+	})
+}
+
+func TestValidateRoleKind(t *testing.T) {
+
+	for _, role := range []*RoleParameters{
+		{Kind: "backup"},
+		{Kind: roleKindSnapshot},
+		{Kind: roleKindSnapshot, Collections: []CollectionAccess{{Name: "orders_*", Match: matchGlob}}},
+		{Kind: roleKindSnapshot, Collections: []CollectionAccess{{Name: "orders", Access: "m"}}},
+		{Kind: roleKindSnapshot, Collections: []CollectionAccess{{Name: "orders"}}, Claims: map[string]interface{}{"access": "r"}},
+		{Kind: roleKindSnapshot, Collections: []CollectionAccess{{Name: "orders"}}, Inherit: []string{"reader"}},
+	} {
+		assert.Error(t, validateRoleKind(role), role)
+	}
+
+	// access defaults to read
+	role := &RoleParameters{Kind: roleKindSnapshot, Collections: []CollectionAccess{{Name: "orders"}, {Name: "users", Access: "rw"}}}
+	assert.NoError(t, validateRoleKind(role))
+	assert.Equal(t, []CollectionAccess{{Name: "orders", Access: "r"}, {Name: "users", Access: "rw"}}, role.Collections)
+
+	assert.NoError(t, validateRoleKind(&RoleParameters{Claims: map[string]interface{}{"access": "r"}}))
+}
+
+func TestSnapshotHistory(t *testing.T) {
+
+	b, reqStorage := getTestBackend(t)
+	ctx := context.Background()
+
+	for i := 0; i < defaultMaxSnapshots+2; i++ {
+		err := recordSnapshot(ctx, reqStorage, "instance1", "orders", &SnapshotRecord{
+			Name:      fmt.Sprintf("orders-%d.snapshot", i),
+			CreatedAt: time.Now().UTC(),
+			EntityID:  "entity-1",
+		})
+		assert.NoError(t, err)
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "snapshot/instance1/orders",
+		Storage:   reqStorage,
+	})
+	assert.NoError(t, err)
+	assert.False(t, resp.IsError())
+
+	snapshots := resp.Data["snapshots"].([]interface{})
+	assert.Len(t, snapshots, defaultMaxSnapshots)
+	assert.Equal(t, "orders-2.snapshot", snapshots[0].(map[string]interface{})["name"])
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "snapshot/instance1",
+		Storage:   reqStorage,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"orders"}, resp.Data["keys"])
+	info := resp.Data["key_info"].(map[string]interface{})["orders"].(map[string]interface{})
+	assert.Equal(t, fmt.Sprintf("orders-%d.snapshot", defaultMaxSnapshots+1), info["last_snapshot"])
+
+	// snapshots of unconfigured instances are refused
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "snapshot/instance2/orders",
+		Storage:   reqStorage,
+	})
+	assert.Error(t, err)
+	assert.Nil(t, resp)
+}
@@ -0,0 +1,361 @@
+package qdrant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	staticCredsPrefix = "static-creds/"
+
+	// roleKindStatic roles hand out a long-lived credential which is
+	// rotated every rotation period
+	roleKindStatic = "static"
+
+	staticSourceJWT         = "jwt"
+	staticSourceReadOnlyKey = "read_only_key"
+)
+
+// StaticCredential is the current credential of a static role
+type StaticCredential struct {
+	Source       string     `json:"source"`
+	Token        string     `json:"token"`
+	Generation   int        `json:"generation"`
+	LastRotated  time.Time  `json:"last_rotated"`
+	NextRotation time.Time  `json:"next_rotation"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+}
+
+func pathStaticCreds(b *QdrantBackend) []*framework.Path {
+
+	fields := map[string]*framework.FieldSchema{
+		"dbId": {
+			Type:        framework.TypeString,
+			Description: "DB identifier",
+			Required:    false,
+		},
+		"role": {
+			Type:        framework.TypeString,
+			Description: "Role name",
+			Required:    false,
+		},
+	}
+
+	return []*framework.Path{
+		{
+			Pattern: staticCredsPrefix + framework.GenericNameRegex("dbId") + "/" + framework.GenericNameRegex("role") + "$",
+			Fields:  fields,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathReadStaticCreds,
+				},
+			},
+			HelpSynopsis:    pathStaticCredsHelpSyn,
+			HelpDescription: pathStaticCredsHelpDesc,
+		},
+		{
+			Pattern: staticCredsPrefix + framework.GenericNameRegex("dbId") + "/" + framework.GenericNameRegex("role") + "/rotate$",
+			Fields:  fields,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathRotateStaticCreds,
+				},
+			},
+			HelpSynopsis:    pathStaticCredsHelpSyn,
+			HelpDescription: pathStaticCredsHelpDesc,
+		},
+	}
+
+}
+
+func (b *QdrantBackend) pathReadStaticCreds(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	cred, err := b.staticCredential(ctx, req, data.Get("dbId").(string), data.Get("role").(string), false)
+	if err != nil {
+		return errorResponse(ReadingStaticCredsFailedError, err)
+	}
+
+	return createResponseStaticCreds(cred)
+}
+
+func (b *QdrantBackend) pathRotateStaticCreds(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	cred, err := b.staticCredential(ctx, req, data.Get("dbId").(string), data.Get("role").(string), true)
+	if err != nil {
+		return errorResponse(RotateStaticCredsFailedError, err)
+	}
+
+	return createResponseStaticCreds(cred)
+}
+
+func createResponseStaticCreds(cred *StaticCredential) (*logical.Response, error) {
+
+	rval := map[string]interface{}{}
+	err := StructToMap(cred, &rval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: rval,
+	}, nil
+}
+
+func readStaticCredential(ctx context.Context, storage logical.Storage, dbId string, role string) (*StaticCredential, error) {
+	return getFromStorage[StaticCredential](ctx, storage, staticCredsPrefix+dbId+"/"+role)
+}
+
+// staticCredential returns the credential of a static role to the
+// requester, rotating it first if it is due, the role changed or force
+// is set
+func (b *QdrantBackend) staticCredential(ctx context.Context, req *logical.Request, dbId string, name string, force bool) (*StaticCredential, error) {
+
+	storage := req.Storage
+
+	config, err := readConfig(ctx, storage, dbId)
+	if err != nil {
+		return nil, err
+	}
+
+	if config == nil {
+		return nil, notFound(ConfigNotFoundError)
+	}
+
+	role, err := readRole(ctx, storage, dbId, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if role == nil {
+		return nil, notFound(RoleNotFoundError)
+	}
+
+	if role.Kind != roleKindStatic {
+		return nil, invalid(fmt.Errorf("role %q is not a static role", name))
+	}
+
+	err = b.checkBindings(req, role)
+	if err != nil {
+		return nil, err
+	}
+
+	if isReadOnlyKeyRole(role) {
+		err = checkReadOnlyKey(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cred, err := readStaticCredential(ctx, storage, dbId, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !force && !rotationDue(config, role, cred, time.Now()) {
+		return cred, nil
+	}
+
+	return b.rotateStaticCredential(ctx, storage, config, role)
+}
+
+// rotationDue reports whether the credential has to be minted again
+func rotationDue(config *ConfigParameters, role *RoleParameters, cred *StaticCredential, now time.Time) bool {
+
+	if cred == nil || cred.Generation != role.Generation || cred.Source != staticSource(role) {
+		return true
+	}
+
+	if cred.Source == staticSourceReadOnlyKey && cred.Token != config.ReadOnlyKey {
+		return true
+	}
+
+	return !now.Before(cred.NextRotation)
+}
+
+// rotateStaticCredential mints and stores a new credential for the role
+func (b *QdrantBackend) rotateStaticCredential(ctx context.Context, storage logical.Storage, config *ConfigParameters, role *RoleParameters) (*StaticCredential, error) {
+
+	period, err := time.ParseDuration(role.RotationPeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+
+	cred := &StaticCredential{
+		Source:       staticSource(role),
+		Generation:   role.Generation,
+		LastRotated:  now,
+		NextRotation: now.Add(period),
+	}
+
+	switch cred.Source {
+	case staticSourceReadOnlyKey:
+		if config.ReadOnlyKey == "" {
+			return nil, invalid(errors.New("instance has no read_only_key"))
+		}
+		err = checkReadOnlyKey(config)
+		if err != nil {
+			return nil, err
+		}
+		cred.Token = config.ReadOnlyKey
+	default:
+		token := JWTParameters{DBId: role.DBId, RoleId: role.RoleId}
+		err = b.generateJWT(ctx, storage, config, role, &token)
+		if err != nil {
+			return nil, err
+		}
+		cred.Token = token.Token
+
+		if token.issued != nil {
+			err = storeIssuance(ctx, storage, role.DBId, token.issued)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		expiry := now.Add(tokenTTL(config, role))
+		cred.ExpiresAt = &expiry
+	}
+
+	err = storeInStorage[StaticCredential](ctx, storage, staticCredsPrefix+role.DBId+"/"+role.RoleId, cred)
+	if err != nil {
+		return nil, err
+	}
+
+	return cred, nil
+}
+
+// rotateStaticCredentials rotates the due credentials of every static
+// role. Failed rotations are logged and retried on the next run.
+func (b *QdrantBackend) rotateStaticCredentials(ctx context.Context, storage logical.Storage) error {
+
+	configs, err := listConfig(ctx, storage)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	for _, dbId := range configs {
+		config, err := readConfig(ctx, storage, dbId)
+		if err != nil {
+			return err
+		}
+		if config == nil {
+			continue
+		}
+
+		roles, err := readAllRoles(ctx, storage, dbId)
+		if err != nil {
+			return err
+		}
+
+		for _, role := range roles {
+			if role.Kind != roleKindStatic {
+				continue
+			}
+
+			cred, err := readStaticCredential(ctx, storage, dbId, role.RoleId)
+			if err != nil {
+				return err
+			}
+
+			if !rotationDue(config, role, cred, now) {
+				continue
+			}
+
+			_, err = b.rotateStaticCredential(ctx, storage, config, role)
+			if err != nil {
+				b.Logger().Error("rotating static credential failed", "instance", dbId, "role", role.RoleId, "error", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// isReadOnlyKeyRole reports whether the role hands out the read-only key
+func isReadOnlyKeyRole(role *RoleParameters) bool {
+	return role.Kind == roleKindStatic && staticSource(role) == staticSourceReadOnlyKey
+}
+
+// readOnlyKeyClaims are the claims the read-only key is checked against
+// max_access with, the key grants global read access
+func readOnlyKeyClaims() map[string]interface{} {
+	return map[string]interface{}{accessClaim: accessRead}
+}
+
+// checkReadOnlyKey checks max_access of the instance allows handing out
+// the read-only key
+func checkReadOnlyKey(config *ConfigParameters) error {
+
+	err := enforceAccessPolicy(config.MaxAccess, readOnlyKeyClaims())
+	if err != nil {
+		return invalid(err)
+	}
+	return nil
+}
+
+func staticSource(role *RoleParameters) string {
+	if role.StaticSource == "" {
+		return staticSourceJWT
+	}
+	return role.StaticSource
+}
+
+// validateStaticRole checks the rotation period and source of a static
+// role. Roles handing out the read-only key carry no claims.
+func validateStaticRole(role *RoleParameters) error {
+
+	period, err := time.ParseDuration(role.RotationPeriod)
+	if err != nil || period <= 0 {
+		return fmt.Errorf("static roles need a positive rotation_period")
+	}
+
+	switch staticSource(role) {
+	case staticSourceJWT:
+		if role.TokenTTL != "" {
+			ttl, err := time.ParseDuration(role.TokenTTL)
+			if err != nil || ttl < period {
+				return errors.New("jwt_ttl of a static role must not be shorter than its rotation_period")
+			}
+		}
+	case staticSourceReadOnlyKey:
+		if len(role.Claims) > 0 || len(role.Collections) > 0 || role.Template != "" || len(role.Inherit) > 0 {
+			return errors.New("read_only_key static roles can not have claims, collections, a template or inherited roles")
+		}
+	default:
+		return fmt.Errorf("unknown static_source %q", role.StaticSource)
+	}
+
+	return nil
+}
+
+const pathStaticCredsHelpSyn = `
+Read the credential of a static role.
+`
+
+const pathStaticCredsHelpDesc = `
+Static roles hand out a long-lived credential for clients which can not
+refresh tokens: a JWT minted from the role claims ('jwt') or the
+instance's read-only API key ('read_only_key'). The credential is
+rotated every rotation_period, when the role changes or when
+static-creds/<instance>/<role>/rotate is written. A JWT stays valid for
+twice the rotation period unless the role sets jwt_ttl.
+`
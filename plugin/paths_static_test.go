@@ -0,0 +1,162 @@
+package qdrant
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticCreds(t *testing.T) {
+
+	b, reqStorage := getTestBackend(t)
+
+	t.Run("Test static credentials", func(t *testing.T) {
+
+		ctx := context.Background()
+
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "config/instance1",
+			Storage:   reqStorage,
+			Data: map[string]interface{}{
+				"url":           "localhost:6334",
+				"sig_key":       "your-very-long-256-bit-secret-key",
+				"sig_alg":       "HS256",
+				"jwt_ttl":       "3s",
+				"read_only_key": "read-only-1",
+			},
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+
+		for _, role := range []*RoleParameters{
+			{DBId: "instance1", RoleId: "legacy", Kind: roleKindStatic, RotationPeriod: "1h", Claims: map[string]interface{}{"access": "r"}, Generation: 1},
+			{DBId: "instance1", RoleId: "viewer", Kind: roleKindStatic, RotationPeriod: "24h", StaticSource: staticSourceReadOnlyKey, Generation: 1},
+			{DBId: "instance1", RoleId: "reader", Claims: map[string]interface{}{"access": "r"}, Generation: 1},
+		} {
+			err := storeInStorage(ctx, reqStorage, "role/"+role.DBId+"/"+role.RoleId, role)
+			assert.NoError(t, err)
+		}
+
+		read := func(path string) (*logical.Response, error) {
+			return b.HandleRequest(ctx, &logical.Request{
+				Operation: logical.ReadOperation,
+				Path:      path,
+				Storage:   reqStorage,
+			})
+		}
+
+		// jwt credentials are minted on first read and kept until rotation
+		resp, err = read("static-creds/instance1/legacy")
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+		assert.Equal(t, "jwt", resp.Data["source"])
+		token := resp.Data["token"].(string)
+		assert.NotEmpty(t, token)
+		assert.NotNil(t, resp.Data["next_rotation"])
+		assert.NotNil(t, resp.Data["expires_at"])
+
+		resp, err = read("static-creds/instance1/legacy")
+		assert.NoError(t, err)
+		assert.Equal(t, token, resp.Data["token"])
+
+		// the read-only key follows the instance config
+		resp, err = read("static-creds/instance1/viewer")
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+		assert.Equal(t, "read-only-1", resp.Data["token"])
+
+		config, err := readConfig(ctx, reqStorage, "instance1")
+		assert.NoError(t, err)
+		config.ReadOnlyKey = "read-only-2"
+		err = storeInStorage(ctx, reqStorage, "config/instance1", config)
+		assert.NoError(t, err)
+
+		resp, err = read("static-creds/instance1/viewer")
+		assert.NoError(t, err)
+		assert.Equal(t, "read-only-2", resp.Data["token"])
+
+		_, err = read("jwt/instance1/viewer")
+		assertErrorCode(t, err, ErrCodeInvalidRequest, http.StatusBadRequest)
+
+		// the read-only key is not handed out once max_access forbids global read
+		config.MaxAccess = &AccessPolicy{GlobalLevels: []string{accessManage}, AllowManage: true}
+		err = storeInStorage(ctx, reqStorage, "config/instance1", config)
+		assert.NoError(t, err)
+
+		_, err = read("static-creds/instance1/viewer")
+		assertErrorCode(t, err, ErrCodeInvalidRequest, http.StatusBadRequest)
+
+		_, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "static-creds/instance1/viewer/rotate",
+			Storage:   reqStorage,
+		})
+		assertErrorCode(t, err, ErrCodeInvalidRequest, http.StatusBadRequest)
+
+		config.MaxAccess = nil
+		err = storeInStorage(ctx, reqStorage, "config/instance1", config)
+		assert.NoError(t, err)
+
+		// only static roles have static credentials
+		_, err = read("static-creds/instance1/reader")
+		assert.Error(t, err)
+
+		// due credentials are rotated by the periodic func
+		cred, err := readStaticCredential(ctx, reqStorage, "instance1", "legacy")
+		assert.NoError(t, err)
+		past := time.Now().Add(-2 * time.Hour).UTC()
+		cred.LastRotated = past
+		cred.NextRotation = past.Add(time.Hour)
+		err = storeInStorage(ctx, reqStorage, "static-creds/instance1/legacy", cred)
+		assert.NoError(t, err)
+
+		err = b.periodicFunc(ctx, &logical.Request{Storage: reqStorage})
+		assert.NoError(t, err)
+
+		cred, err = readStaticCredential(ctx, reqStorage, "instance1", "legacy")
+		assert.NoError(t, err)
+		assert.True(t, cred.LastRotated.After(past))
+		assert.True(t, cred.NextRotation.After(time.Now()))
+
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "static-creds/instance1/legacy/rotate",
+			Storage:   reqStorage,
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+	})
+}
+
+func TestValidateStaticRole(t *testing.T) {
+
+	for _, role := range []*RoleParameters{
+		{Kind: roleKindStatic},
+		{Kind: roleKindStatic, RotationPeriod: "-1h"},
+		{Kind: roleKindStatic, RotationPeriod: "24h", TokenTTL: "1h"},
+		{Kind: roleKindStatic, RotationPeriod: "24h", StaticSource: "api_key"},
+		{Kind: roleKindStatic, RotationPeriod: "24h", StaticSource: staticSourceReadOnlyKey, Claims: map[string]interface{}{"access": "r"}},
+	} {
+		assert.Error(t, validateRoleKind(role), role)
+	}
+
+	assert.NoError(t, validateRoleKind(&RoleParameters{Kind: roleKindStatic, RotationPeriod: "24h", TokenTTL: "48h"}))
+
+	role := &RoleParameters{Kind: roleKindStatic, RotationPeriod: "1h", Generation: 2}
+	config := &ConfigParameters{TokenTTL: "3s"}
+	assert.Equal(t, 2*time.Hour, tokenTTL(config, role))
+
+	now := time.Now()
+	cred := &StaticCredential{Source: staticSourceJWT, Generation: 2, NextRotation: now.Add(time.Minute)}
+	assert.False(t, rotationDue(config, role, cred, now))
+	assert.True(t, rotationDue(config, role, cred, now.Add(time.Minute)))
+	assert.True(t, rotationDue(config, role, nil, now))
+
+	role.Generation = 3
+	assert.True(t, rotationDue(config, role, cred, now))
+}
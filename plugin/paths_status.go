@@ -0,0 +1,152 @@
+package qdrant
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	statusPrefix = "status/"
+)
+
+// InstanceStatus reports the reachability of an instance and the state
+// of its role collection
+type InstanceStatus struct {
+	Reachable bool    `json:"reachable"`
+	LatencyMs float64 `json:"latency_ms,omitempty"`
+	Version   string  `json:"version,omitempty"`
+	ErrorCode string  `json:"error_code,omitempty"`
+	Error     string  `json:"error,omitempty"`
+
+	SysRolesExists  bool   `json:"sys_roles_exists"`
+	RoleIndexExists bool   `json:"role_index_exists"`
+	SysRolesPoints  uint64 `json:"sys_roles_points"`
+	Roles           int    `json:"roles"`
+
+	SysRolesErrorCode string `json:"sys_roles_error_code,omitempty"`
+	SysRolesError     string `json:"sys_roles_error,omitempty"`
+
+	LastSynced   *time.Time `json:"last_synced,omitempty"`
+	LastVerified *time.Time `json:"last_verified,omitempty"`
+	Bootstrapped *time.Time `json:"bootstrapped,omitempty"`
+}
+
+func pathStatus(b *QdrantBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: statusPrefix + framework.GenericNameRegex("dbId") + "$",
+			Fields: map[string]*framework.FieldSchema{
+
+				"dbId": {
+					Type:        framework.TypeString,
+					Description: "DB identifier",
+					Required:    false,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathReadStatus,
+				},
+			},
+			HelpSynopsis:    pathStatusHelpSyn,
+			HelpDescription: pathStatusHelpDesc,
+		},
+	}
+
+}
+
+func (b *QdrantBackend) pathReadStatus(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	status, err := b.instanceStatus(ctx, req.Storage, data.Get("dbId").(string))
+	if err != nil {
+		return errorResponse(ReadingStatusFailedError, err)
+	}
+
+	rval := map[string]interface{}{}
+	err = StructToMap(status, &rval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: rval,
+	}, nil
+}
+
+// instanceStatus reports the state of an instance. An unreachable
+// instance is reported with its error rather than failing the request.
+func (b *QdrantBackend) instanceStatus(ctx context.Context, storage logical.Storage, dbId string) (*InstanceStatus, error) {
+
+	config, err := readConfig(ctx, storage, dbId)
+	if err != nil {
+		return nil, err
+	}
+
+	if config == nil {
+		return nil, notFound(ConfigNotFoundError)
+	}
+
+	roles, err := listRole(ctx, storage, dbId)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := readInstanceState(ctx, storage, dbId)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &InstanceStatus{
+		Roles: len(roles),
+	}
+
+	if state != nil {
+		status.LastSynced = state.LastSynced
+		status.LastVerified = state.LastVerified
+		status.Bootstrapped = state.Bootstrapped
+	}
+
+	registry, err := b.registry.Status(ctx, storage, dbId)
+	if err != nil {
+		status.ErrorCode, _ = classifyError(err)
+		status.Error = err.Error()
+		return status, nil
+	}
+
+	status.Reachable = true
+	status.LatencyMs = float64(registry.Latency.Microseconds()) / 1000
+	status.Version = registry.Version
+	status.SysRolesExists = registry.Exists
+	status.RoleIndexExists = registry.RoleIndexExists
+	status.SysRolesPoints = registry.Points
+
+	if registry.RegistryErr != nil {
+		status.SysRolesErrorCode, _ = classifyError(registry.RegistryErr)
+		status.SysRolesError = registry.RegistryErr.Error()
+	}
+
+	return status, nil
+}
+
+const pathStatusHelpSyn = `
+Report the health of an instance.
+`
+
+const pathStatusHelpDesc = `
+Reading status/<instance> checks the instance answers and reports the
+round-trip latency, the server version, whether the sys_roles collection
+and its 'role' payload index exist, the number of sys_roles points
+against the number of roles stored in Vault and the time of the last
+successful sync. An unreachable instance is reported with
+'reachable' false and the error instead of failing the request. If the
+instance answers but sys_roles can not be described, the failure is
+reported in 'sys_roles_error'.
+`
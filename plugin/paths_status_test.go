@@ -0,0 +1,97 @@
+package qdrant
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/migrx-io/vault-plugin-secrets-qdrant/plugin/qdranttest"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestInstanceStatus(t *testing.T) {
+
+	b, reqStorage, srv := getTestBackendWithServer(t)
+	ctx := context.Background()
+
+	readStatus := func() InstanceStatus {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "status/instance1",
+			Storage:   reqStorage,
+		})
+		assert.NoError(t, err)
+
+		var current InstanceStatus
+		MapToStruct(resp.Data, &current)
+		return current
+	}
+
+	_, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "status/instance1",
+		Storage:   reqStorage,
+	})
+	assertErrorCode(t, err, ErrCodeNotFound, http.StatusNotFound)
+
+	writeTestConfig(t, b, reqStorage, nil)
+
+	// no roles synced yet
+	current := readStatus()
+	assert.True(t, current.Reachable)
+	assert.Equal(t, "qdranttest", current.Version)
+	assert.False(t, current.SysRolesExists)
+	assert.False(t, current.RoleIndexExists)
+	assert.Zero(t, current.Roles)
+	assert.Nil(t, current.LastSynced)
+
+	for _, name := range []string{"read", "write"} {
+		_, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "role/instance1/" + name,
+			Storage:   reqStorage,
+			Data:      map[string]interface{}{"claims": map[string]interface{}{"access": "r"}},
+		})
+		assert.NoError(t, err)
+	}
+
+	current = readStatus()
+	assert.True(t, current.Reachable)
+	assert.True(t, current.SysRolesExists)
+	assert.True(t, current.RoleIndexExists)
+	assert.Equal(t, uint64(2), current.SysRolesPoints)
+	assert.Equal(t, 2, current.Roles)
+	assert.NotNil(t, current.LastSynced)
+	assert.Empty(t, current.Error)
+
+	// points removed outside of Vault
+	srv.RemovePoints(SYS_ROLE_TABLE, "role", "write")
+
+	current = readStatus()
+	assert.Equal(t, uint64(1), current.SysRolesPoints)
+	assert.Equal(t, 2, current.Roles)
+
+	// sys_roles failures of a reachable instance are reported apart
+	srv.FailOnce(qdranttest.MethodPointsCount, status.Error(codes.PermissionDenied, "access denied"))
+
+	current = readStatus()
+	assert.True(t, current.Reachable)
+	assert.Equal(t, "qdranttest", current.Version)
+	assert.True(t, current.SysRolesExists)
+	assert.Empty(t, current.Error)
+	assert.Equal(t, ErrCodeQdrantPermissionDenied, current.SysRolesErrorCode)
+	assert.Contains(t, current.SysRolesError, "access denied")
+
+	// unreachable instances are reported, not failed
+	srv.Fail(qdranttest.MethodHealthCheck, status.Error(codes.Unavailable, "connection refused"))
+
+	current = readStatus()
+	assert.False(t, current.Reachable)
+	assert.Equal(t, ErrCodeQdrantUnavailable, current.ErrorCode)
+	assert.Contains(t, current.Error, "connection refused")
+	assert.Equal(t, 2, current.Roles)
+	assert.NotNil(t, current.LastSynced)
+}
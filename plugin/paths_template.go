@@ -0,0 +1,459 @@
+package qdrant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	templatePath   = "template"
+	templatePrefix = "template/"
+)
+
+// TemplateParameters holds parameterised claims shared by roles.
+// String values may contain '{{var}}' placeholders which are
+// replaced with the role's vars.
+type TemplateParameters struct {
+	DBId        string                 `json:"dbId"`
+	Name        string                 `json:"name"`
+	Claims      map[string]interface{} `json:"claims"`
+	Collections []CollectionAccess     `json:"collections,omitempty"`
+}
+
+var templateVarRegex = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+func pathTemplate(b *QdrantBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: templatePrefix + framework.GenericNameRegex("dbId") + "/" + framework.GenericNameRegex("name") + "$",
+			Fields: map[string]*framework.FieldSchema{
+
+				"dbId": {
+					Type:        framework.TypeString,
+					Description: "DB identifier",
+					Required:    false,
+				},
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Template name",
+					Required:    false,
+				},
+
+				"claims": {
+					Type:        framework.TypeMap,
+					Description: `JSON claims set with '{{var}}' placeholders.`,
+				},
+
+				"collections": {
+					Type:        framework.TypeSlice,
+					Description: `List of collection access entries with '{{var}}' placeholders.`,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.CreateOperation: &framework.PathOperation{
+					Callback: b.pathAddTemplate,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathAddTemplate,
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathReadTemplate,
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.pathDeleteTemplate,
+				},
+			},
+			HelpSynopsis:    pathTemplateHelpSyn,
+			HelpDescription: pathTemplateHelpDesc,
+		},
+		{
+			Pattern: templatePrefix + framework.GenericNameRegex("dbId") + "?$",
+			Fields: map[string]*framework.FieldSchema{
+
+				"dbId": {
+					Type:        framework.TypeString,
+					Description: "DB identifier",
+					Required:    false,
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.pathListTemplate,
+				},
+			},
+			HelpSynopsis:    pathTemplateHelpSyn,
+			HelpDescription: pathTemplateHelpDesc,
+		},
+	}
+
+}
+
+func (b *QdrantBackend) pathAddTemplate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	jsonString, err := json.Marshal(data.Raw)
+
+	b.Logger().Debug("pathAddTemplate", jsonString)
+
+	if err != nil {
+		return invalidRequestResponse(DecodeFailedError, err)
+	}
+	params := TemplateParameters{}
+	json.Unmarshal(jsonString, &params)
+
+	warnings, err := b.addTemplate(ctx, req.Storage, params)
+
+	if err != nil {
+		return errorResponse(AddingTemplateFailedError, err)
+	}
+
+	if len(warnings) > 0 {
+		resp := &logical.Response{}
+		for _, w := range warnings {
+			resp.AddWarning(w)
+		}
+		return resp, nil
+	}
+	return nil, nil
+}
+
+func (b *QdrantBackend) pathReadTemplate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	jsonString, err := json.Marshal(data.Raw)
+	if err != nil {
+		return invalidRequestResponse(DecodeFailedError, err)
+	}
+	params := TemplateParameters{}
+	json.Unmarshal(jsonString, &params)
+
+	template, err := readTemplate(ctx, req.Storage, params.DBId, params.Name)
+
+	if err != nil {
+		return errorResponse(ReadingTemplateFailedError, err)
+	}
+
+	if template == nil {
+		return notFoundResponse(TemplateNotFoundError)
+	}
+
+	rval := map[string]interface{}{}
+	err = StructToMap(template, &rval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: rval,
+	}, nil
+
+}
+
+func (b *QdrantBackend) pathListTemplate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	jsonString, err := json.Marshal(data.Raw)
+	if err != nil {
+		return invalidRequestResponse(DecodeFailedError, err)
+	}
+	params := TemplateParameters{}
+	json.Unmarshal(jsonString, &params)
+
+	entries, err := listTemplate(ctx, req.Storage, params.DBId)
+	if err != nil {
+		return errorResponse(ListTemplateFailedError, err)
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
+func (b *QdrantBackend) pathDeleteTemplate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+
+	err := data.Validate()
+	if err != nil {
+		return invalidRequestResponse(InvalidParametersError, err)
+	}
+
+	jsonString, err := json.Marshal(data.Raw)
+	if err != nil {
+		return invalidRequestResponse(DecodeFailedError, err)
+	}
+	params := TemplateParameters{}
+	json.Unmarshal(jsonString, &params)
+
+	// templates in use by roles can not be deleted
+	dependents, err := templateDependents(ctx, req.Storage, params.DBId, params.Name)
+	if err != nil {
+		return errorResponse(DeleteTemplateFailedError, err)
+	}
+
+	if len(dependents) > 0 {
+		return conflictResponse(DeleteTemplateFailedError, errors.New("template is used by roles: "+strings.Join(dependents, ", ")))
+	}
+
+	err = deleteFromStorage(ctx, req.Storage, templatePrefix+params.DBId+"/"+params.Name)
+	if err != nil {
+		return invalidRequestResponse(DeleteTemplateFailedError, err)
+	}
+	return nil, nil
+
+}
+
+// addTemplate stores the template and pushes every dependent role to
+// the instance again. Dependent roles which fail to sync are returned
+// as warnings.
+func (b *QdrantBackend) addTemplate(ctx context.Context, storage logical.Storage, params TemplateParameters) ([]string, error) {
+
+	path := templatePrefix + params.DBId + "/" + params.Name
+
+	b.Logger().Debug("add template path", path)
+
+	config, err := readConfig(ctx, storage, params.DBId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if config == nil {
+		return nil, notFound(ConfigNotFoundError)
+	}
+
+	for _, c := range params.Collections {
+		if c.Name == "" {
+			return nil, invalid(errors.New("collections: missing name"))
+		}
+	}
+
+	dependents, err := templateDependents(ctx, storage, params.DBId, params.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// resolve dependent roles with the new template before storing it
+	var roles []*RoleParameters
+	for _, name := range dependents {
+		role, err := readRole(ctx, storage, params.DBId, name)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			continue
+		}
+
+		resolved, err := resolveRoleWith(ctx, storage, role, &params)
+		if err != nil {
+			return nil, fmt.Errorf("role %q: %w", name, err)
+		}
+
+		err = validateResolvedRole(resolved)
+		if err != nil {
+			return nil, invalid(fmt.Errorf("role %q: %w", name, err))
+		}
+
+		roles = append(roles, role)
+	}
+
+	err = storeInStorage[TemplateParameters](ctx, storage, path, &params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// push regenerated roles
+	var warnings []string
+	for _, role := range roles {
+		err = b.registry.PutRoles(ctx, storage, role.DBId, []string{role.RoleId})
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("role %q sync failed: %s", role.RoleId, err))
+		}
+
+		err = recordRoleSync(ctx, storage, params.DBId, []string{role.RoleId}, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return warnings, nil
+
+}
+
+func readTemplate(ctx context.Context, storage logical.Storage, dbId string, name string) (*TemplateParameters, error) {
+	path := templatePrefix + dbId + "/" + name
+
+	return getFromStorage[TemplateParameters](ctx, storage, path)
+}
+
+func listTemplate(ctx context.Context, storage logical.Storage, dbId string) ([]string, error) {
+
+	path := templatePrefix + dbId + "/"
+
+	l, err := storage.List(ctx, path)
+
+	if err != nil {
+		return nil, err
+	}
+	var templates []string
+	for _, v := range l {
+		templates = append(templates, v)
+	}
+	return templates, nil
+}
+
+// templateDependents returns the roles using the template, directly
+// or by inheriting from a role which uses it
+func templateDependents(ctx context.Context, storage logical.Storage, dbId string, name string) ([]string, error) {
+
+	roles, err := readAllRoles(ctx, storage, dbId)
+	if err != nil {
+		return nil, err
+	}
+
+	dependent := map[string]bool{}
+	for _, role := range roles {
+		if role.Template == name {
+			dependent[role.RoleId] = true
+		}
+	}
+
+	// follow inheritance until no new dependents are found
+	for changed := true; changed; {
+		changed = false
+		for _, role := range roles {
+			if dependent[role.RoleId] {
+				continue
+			}
+			for _, parent := range role.Inherit {
+				if dependent[parent] {
+					dependent[role.RoleId] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	var names []string
+	for _, role := range roles {
+		if dependent[role.RoleId] {
+			names = append(names, role.RoleId)
+		}
+	}
+	return names, nil
+}
+
+// renderTemplate replaces '{{var}}' placeholders in template values
+func renderTemplate(template *TemplateParameters, vars map[string]string) (map[string]interface{}, []CollectionAccess, error) {
+
+	var claims map[string]interface{}
+	if template.Claims != nil {
+		v, err := renderValue(template.Claims, vars)
+		if err != nil {
+			return nil, nil, err
+		}
+		claims = v.(map[string]interface{})
+	}
+
+	var collections []CollectionAccess
+	for _, c := range template.Collections {
+		name, err := renderString(c.Name, vars)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		payload, err := renderValue(c.Payload, vars)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		c.Name = name
+		c.Payload, _ = payload.(map[string]interface{})
+		collections = append(collections, c)
+	}
+
+	return claims, collections, nil
+}
+
+func renderValue(value interface{}, vars map[string]string) (interface{}, error) {
+
+	switch v := value.(type) {
+	case string:
+		return renderString(v, vars)
+	case map[string]interface{}:
+		if v == nil {
+			return v, nil
+		}
+		out := map[string]interface{}{}
+		for k, item := range v {
+			r, err := renderValue(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			r, err := renderValue(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, r)
+		}
+		return out, nil
+	default:
+		return value, nil
+	}
+}
+
+func renderString(s string, vars map[string]string) (string, error) {
+
+	var missing []string
+
+	out := templateVarRegex.ReplaceAllStringFunc(s, func(m string) string {
+		name := templateVarRegex.FindStringSubmatch(m)[1]
+		v, ok := vars[name]
+		if !ok {
+			missing = append(missing, name)
+			return m
+		}
+		return v
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing template vars: %s", strings.Join(missing, ", "))
+	}
+
+	return out, nil
+}
+
+const pathTemplateHelpSyn = `
+Configure role templates.
+`
+
+const pathTemplateHelpDesc = `
+Configure role templates.
+
+name:              Template name.
+claims:            JSON claims with '{{var}}' placeholders.
+collections:       List of collection access entries with '{{var}}' placeholders.
+`
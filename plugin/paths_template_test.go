@@ -0,0 +1,178 @@
+package qdrant
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCRUDTemplate(t *testing.T) {
+
+	b, reqStorage := getTestBackend(t)
+
+	t.Run("Test templates", func(t *testing.T) {
+
+		var current TemplateParameters
+
+		pathConfig := "config/instance1"
+		pathTemplate := "template/instance1/tenant"
+
+		// first create config
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      pathConfig,
+			Storage:   reqStorage,
+			Data: map[string]interface{}{
+				"url":     "localhost:6334",
+				"sig_key": "your-very-long-256-bit-secret-key",
+				"sig_alg": "HS256",
+				"jwt_ttl": "3s",
+			},
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+
+		// create template
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      pathTemplate,
+			Storage:   reqStorage,
+			Data: map[string]interface{}{
+				"collections": []interface{}{
+					map[string]interface{}{
+						"name":    "events_{{tenant}}",
+						"access":  "r",
+						"payload": map[string]interface{}{"tenant": "{{tenant}}"},
+					},
+				},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Nil(t, resp)
+
+		// list templates
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      "template/instance1",
+			Storage:   reqStorage,
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+		assert.Equal(t, resp.Data, map[string]interface{}{
+			"keys": []string{"tenant"},
+		})
+
+		// read template
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      pathTemplate,
+			Storage:   reqStorage,
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+
+		MapToStruct(resp.Data, &current)
+		assert.Equal(t, "tenant", current.Name)
+		assert.Equal(t, "events_{{tenant}}", current.Collections[0].Name)
+
+		// store roles using the template directly, without syncing
+		acme := &RoleParameters{
+			DBId:     "instance1",
+			RoleId:   "acme",
+			Template: "tenant",
+			Vars:     map[string]string{"tenant": "acme"},
+		}
+		err = storeInStorage(context.Background(), reqStorage, "role/instance1/acme", acme)
+		assert.NoError(t, err)
+
+		child := &RoleParameters{
+			DBId:        "instance1",
+			RoleId:      "acme-writer",
+			Inherit:     []string{"acme"},
+			Collections: []CollectionAccess{{Name: "events_acme", Access: "rw"}},
+		}
+		err = storeInStorage(context.Background(), reqStorage, "role/instance1/acme-writer", child)
+		assert.NoError(t, err)
+
+		// resolve template vars and inheritance
+		resolved, err := resolveRole(context.Background(), reqStorage, acme)
+		assert.NoError(t, err)
+		assert.Equal(t, []CollectionAccess{
+			{Name: "events_acme", Access: "r", Payload: map[string]interface{}{"tenant": "acme"}},
+		}, resolved.Collections)
+
+		resolved, err = resolveRole(context.Background(), reqStorage, child)
+		assert.NoError(t, err)
+		assert.Equal(t, []CollectionAccess{
+			{Name: "events_acme", Access: "rw"},
+		}, resolved.Collections)
+
+		// read role shows resolved claims
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "role/instance1/acme",
+			Storage:   reqStorage,
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"collection": "events_acme", "access": "r", "payload": map[string]interface{}{"tenant": "acme"}},
+		}, resp.Data["resolved_claims"].(map[string]interface{})["access"])
+
+		// missing vars
+		_, err = resolveRole(context.Background(), reqStorage, &RoleParameters{
+			DBId:     "instance1",
+			RoleId:   "novars",
+			Template: "tenant",
+		})
+		assert.Error(t, err)
+
+		// inheritance cycle
+		_, err = resolveRole(context.Background(), reqStorage, &RoleParameters{
+			DBId:    "instance1",
+			RoleId:  "acme",
+			Inherit: []string{"acme-writer"},
+		})
+		assert.Error(t, err)
+
+		// template and inherited roles in use can not be deleted
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.DeleteOperation,
+			Path:      pathTemplate,
+			Storage:   reqStorage,
+		})
+		assert.Nil(t, resp)
+		assertErrorCode(t, err, ErrCodeConflict, http.StatusConflict)
+
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.DeleteOperation,
+			Path:      "role/instance1/acme",
+			Storage:   reqStorage,
+		})
+		assert.Nil(t, resp)
+		assertErrorCode(t, err, ErrCodeConflict, http.StatusConflict)
+
+		// remove dependents and delete template
+		reqStorage.Delete(context.Background(), "role/instance1/acme-writer")
+		reqStorage.Delete(context.Background(), "role/instance1/acme")
+
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.DeleteOperation,
+			Path:      pathTemplate,
+			Storage:   reqStorage,
+		})
+		assert.NoError(t, err)
+		assert.Nil(t, resp)
+
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      "template/instance1",
+			Storage:   reqStorage,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, resp.Data, map[string]interface{}{})
+	})
+}
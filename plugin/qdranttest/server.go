@@ -0,0 +1,700 @@
+// Package qdranttest provides an in-memory Qdrant gRPC server for
+// tests. It implements the Qdrant health check and the Collections,
+// Points and Snapshots services
+// used by the plugin over a bufconn listener, checks the 'api-key'
+// metadata and supports failure and delay injection per method.
+package qdranttest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/qdrant/go-client/qdrant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Full method names accepted by Fail, FailOnce, Delay and Calls
+const (
+	MethodCollectionsGet         = "/qdrant.Collections/Get"
+	MethodCollectionsList        = "/qdrant.Collections/List"
+	MethodCollectionsCreate      = "/qdrant.Collections/Create"
+	MethodCollectionsDelete      = "/qdrant.Collections/Delete"
+	MethodCollectionExists       = "/qdrant.Collections/CollectionExists"
+	MethodListCollectionAliases  = "/qdrant.Collections/ListCollectionAliases"
+	MethodPointsUpsert           = "/qdrant.Points/Upsert"
+	MethodPointsDelete           = "/qdrant.Points/Delete"
+	MethodPointsCreateFieldIndex = "/qdrant.Points/CreateFieldIndex"
+	MethodPointsCount            = "/qdrant.Points/Count"
+	MethodPointsSetPayload       = "/qdrant.Points/SetPayload"
+	MethodPointsScroll           = "/qdrant.Points/Scroll"
+	MethodSnapshotsCreate        = "/qdrant.Snapshots/Create"
+	MethodHealthCheck            = "/qdrant.Qdrant/HealthCheck"
+)
+
+const bufSize = 1024 * 1024
+
+// Server is an in-memory Qdrant instance
+type Server struct {
+	apiKey   string
+	listener *bufconn.Listener
+	grpc     *grpc.Server
+
+	mu          sync.Mutex
+	collections map[string]*collection
+	aliases     map[string]string
+	failures    map[string]*failure
+	delays      map[string]time.Duration
+	calls       map[string]int
+	metadata    map[string]metadata.MD
+	snapshots   int
+	points      int
+}
+
+// the services are separate types as their method names overlap
+type qdrantService struct {
+	pb.UnimplementedQdrantServer
+	*Server
+}
+
+type collectionsService struct {
+	pb.UnimplementedCollectionsServer
+	*Server
+}
+
+type pointsService struct {
+	pb.UnimplementedPointsServer
+	*Server
+}
+
+type snapshotsService struct {
+	pb.UnimplementedSnapshotsServer
+	*Server
+}
+
+type collection struct {
+	vectors *pb.VectorsConfig
+	points  map[string]*pb.PointStruct
+	indexes map[string]pb.FieldType
+}
+
+type failure struct {
+	err  error
+	once bool
+}
+
+// NewServer starts a server accepting requests with the API key. An
+// empty key accepts every request.
+func NewServer(apiKey string) *Server {
+
+	s := &Server{
+		apiKey:      apiKey,
+		listener:    bufconn.Listen(bufSize),
+		collections: map[string]*collection{},
+		aliases:     map[string]string{},
+		failures:    map[string]*failure{},
+		delays:      map[string]time.Duration{},
+		calls:       map[string]int{},
+		metadata:    map[string]metadata.MD{},
+	}
+
+	s.grpc = grpc.NewServer(grpc.UnaryInterceptor(s.intercept))
+	pb.RegisterQdrantServer(s.grpc, qdrantService{Server: s})
+	pb.RegisterCollectionsServer(s.grpc, collectionsService{Server: s})
+	pb.RegisterPointsServer(s.grpc, pointsService{Server: s})
+	pb.RegisterSnapshotsServer(s.grpc, snapshotsService{Server: s})
+
+	go s.grpc.Serve(s.listener)
+
+	return s
+}
+
+// Close stops the server
+func (s *Server) Close() {
+	s.grpc.Stop()
+	s.listener.Close()
+}
+
+// Dialer returns a dialer connecting to the server
+func (s *Server) Dialer() func(context.Context, string) (net.Conn, error) {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		return s.listener.DialContext(ctx)
+	}
+}
+
+// Fail makes every call of the method return err until Reset
+func (s *Server) Fail(method string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[method] = &failure{err: err}
+}
+
+// FailOnce makes the next call of the method return err
+func (s *Server) FailOnce(method string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[method] = &failure{err: err, once: true}
+}
+
+// Delay holds every call of the method for d, or until the call's
+// deadline
+func (s *Server) Delay(method string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delays[method] = d
+}
+
+// Reset removes injected failures and delays and resets call counts
+// and metadata
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = map[string]*failure{}
+	s.delays = map[string]time.Duration{}
+	s.calls = map[string]int{}
+	s.metadata = map[string]metadata.MD{}
+}
+
+// Calls returns the number of calls of the method
+func (s *Server) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+// Metadata returns the incoming metadata of the last call of the method
+func (s *Server) Metadata(method string) metadata.MD {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.metadata[method].Copy()
+}
+
+// AddCollection creates an empty collection with a single vector
+func (s *Server) AddCollection(name string) {
+	s.AddCollectionWithVectors(name, &pb.VectorsConfig{Config: &pb.VectorsConfig_Params{
+		Params: &pb.VectorParams{Size: 1, Distance: pb.Distance_Dot},
+	}})
+}
+
+// AddCollectionWithVectors creates an empty collection with the vectors
+func (s *Server) AddCollectionWithVectors(name string, vectors *pb.VectorsConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.collections[name] = newCollection(vectors)
+}
+
+// AddPoint adds a point with the string payload to a collection, e.g.
+// to simulate points written by an earlier plugin version
+func (s *Server) AddPoint(name string, payload map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.collections[name]
+	if !ok {
+		return
+	}
+
+	s.points++
+	p := &pb.PointStruct{
+		Id:      &pb.PointId{PointIdOptions: &pb.PointId_Num{Num: uint64(s.points)}},
+		Payload: map[string]*pb.Value{},
+	}
+	for k, v := range payload {
+		p.Payload[k] = &pb.Value{Kind: &pb.Value_StringValue{StringValue: v}}
+	}
+	c.points[pointID(p.Id)] = p
+}
+
+// AddAlias points the alias at the collection
+func (s *Server) AddAlias(alias string, collection string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.aliases[alias] = collection
+}
+
+// Collections returns the sorted collection names
+func (s *Server) Collections() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.collectionNames()
+}
+
+// Indexes returns the payload indexes of a collection
+func (s *Server) Indexes(name string) map[string]pb.FieldType {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	indexes := map[string]pb.FieldType{}
+	if c, ok := s.collections[name]; ok {
+		for k, v := range c.indexes {
+			indexes[k] = v
+		}
+	}
+	return indexes
+}
+
+// PayloadValues returns the sorted string values of the payload key
+// over the points of a collection
+func (s *Server) PayloadValues(name string, key string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var values []string
+	if c, ok := s.collections[name]; ok {
+		for _, p := range c.points {
+			if v, ok := p.Payload[key]; ok {
+				values = append(values, v.GetStringValue())
+			}
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+// RemovePoints removes the points of a collection whose string
+// payload key has the value, e.g. to simulate drift
+func (s *Server) RemovePoints(name string, key string, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.collections[name]; ok {
+		for id, p := range c.points {
+			if v, ok := p.Payload[key]; ok && v.GetStringValue() == value {
+				delete(c.points, id)
+			}
+		}
+	}
+}
+
+func (s *Server) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	s.mu.Lock()
+	s.calls[info.FullMethod]++
+	s.metadata[info.FullMethod] = md
+	delay := s.delays[info.FullMethod]
+	var err error
+	if f, ok := s.failures[info.FullMethod]; ok {
+		err = f.err
+		if f.once {
+			delete(s.failures, info.FullMethod)
+		}
+	}
+	s.mu.Unlock()
+
+	if s.apiKey != "" {
+		keys := md.Get("api-key")
+		if len(keys) == 0 || keys[0] != s.apiKey {
+			return nil, status.Error(codes.PermissionDenied, "Invalid api-key")
+		}
+	}
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func newCollection(vectors *pb.VectorsConfig) *collection {
+	return &collection{
+		vectors: vectors,
+		points:  map[string]*pb.PointStruct{},
+		indexes: map[string]pb.FieldType{},
+	}
+}
+
+func (s *Server) collectionNames() []string {
+	var names []string
+	for name := range s.collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Server) collection(name string) (*collection, error) {
+	if target, ok := s.aliases[name]; ok {
+		name = target
+	}
+	c, ok := s.collections[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "Collection `%s` doesn't exist!", name)
+	}
+	return c, nil
+}
+
+// Qdrant service
+
+func (s qdrantService) HealthCheck(ctx context.Context, in *pb.HealthCheckRequest) (*pb.HealthCheckReply, error) {
+	return &pb.HealthCheckReply{Title: "qdrant - vector search engine", Version: "qdranttest"}, nil
+}
+
+// Collections service
+
+func (s collectionsService) Get(ctx context.Context, in *pb.GetCollectionInfoRequest) (*pb.GetCollectionInfoResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.collection(in.CollectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	// payload schema types are the field types shifted by one for
+	// PayloadSchemaType_UnknownType
+	schema := map[string]*pb.PayloadSchemaInfo{}
+	for name, fieldType := range c.indexes {
+		schema[name] = &pb.PayloadSchemaInfo{DataType: pb.PayloadSchemaType(fieldType + 1)}
+	}
+
+	points := uint64(len(c.points))
+	return &pb.GetCollectionInfoResponse{
+		Result: &pb.CollectionInfo{
+			Status:        pb.CollectionStatus_Green,
+			PointsCount:   &points,
+			PayloadSchema: schema,
+			Config: &pb.CollectionConfig{
+				Params: &pb.CollectionParams{VectorsConfig: c.vectors},
+			},
+		},
+	}, nil
+}
+
+func (s collectionsService) List(ctx context.Context, in *pb.ListCollectionsRequest) (*pb.ListCollectionsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &pb.ListCollectionsResponse{}
+	for _, name := range s.collectionNames() {
+		resp.Collections = append(resp.Collections, &pb.CollectionDescription{Name: name})
+	}
+	return resp, nil
+}
+
+func (s collectionsService) Create(ctx context.Context, in *pb.CreateCollection) (*pb.CollectionOperationResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.collections[in.CollectionName]; ok {
+		return nil, status.Errorf(codes.AlreadyExists, "Collection `%s` already exists!", in.CollectionName)
+	}
+
+	s.collections[in.CollectionName] = newCollection(in.VectorsConfig)
+	return &pb.CollectionOperationResponse{Result: true}, nil
+}
+
+func (s collectionsService) Delete(ctx context.Context, in *pb.DeleteCollection) (*pb.CollectionOperationResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.collections[in.CollectionName]
+	delete(s.collections, in.CollectionName)
+	for alias, target := range s.aliases {
+		if target == in.CollectionName {
+			delete(s.aliases, alias)
+		}
+	}
+	return &pb.CollectionOperationResponse{Result: ok}, nil
+}
+
+func (s collectionsService) CollectionExists(ctx context.Context, in *pb.CollectionExistsRequest) (*pb.CollectionExistsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.collections[in.CollectionName]
+	return &pb.CollectionExistsResponse{Result: &pb.CollectionExists{Exists: ok}}, nil
+}
+
+func (s collectionsService) ListCollectionAliases(ctx context.Context, in *pb.ListCollectionAliasesRequest) (*pb.ListAliasesResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.collections[in.CollectionName]; !ok {
+		return nil, status.Errorf(codes.NotFound, "Collection `%s` doesn't exist!", in.CollectionName)
+	}
+
+	resp := &pb.ListAliasesResponse{}
+	for alias, target := range s.aliases {
+		if target == in.CollectionName {
+			resp.Aliases = append(resp.Aliases, &pb.AliasDescription{AliasName: alias, CollectionName: target})
+		}
+	}
+	return resp, nil
+}
+
+// Points service
+
+func (s pointsService) Upsert(ctx context.Context, in *pb.UpsertPoints) (*pb.PointsOperationResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.collection(in.CollectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range in.Points {
+		c.points[pointID(p.Id)] = p
+	}
+	return completed(), nil
+}
+
+func (s pointsService) Delete(ctx context.Context, in *pb.DeletePoints) (*pb.PointsOperationResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.collection(in.CollectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter := in.GetPoints().GetFilter(); filter != nil {
+		for id, p := range c.points {
+			if matchFilter(filter, p) {
+				delete(c.points, id)
+			}
+		}
+	}
+	for _, id := range in.GetPoints().GetPoints().GetIds() {
+		delete(c.points, pointID(id))
+	}
+	return completed(), nil
+}
+
+func (s pointsService) CreateFieldIndex(ctx context.Context, in *pb.CreateFieldIndexCollection) (*pb.PointsOperationResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.collection(in.CollectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	c.indexes[in.FieldName] = in.GetFieldType()
+	return completed(), nil
+}
+
+func (s pointsService) SetPayload(ctx context.Context, in *pb.SetPayloadPoints) (*pb.PointsOperationResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.collection(in.CollectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	for id, p := range c.points {
+		selector := in.GetPointsSelector()
+		if selector != nil && !matchSelector(selector, id, p) {
+			continue
+		}
+		if p.Payload == nil {
+			p.Payload = map[string]*pb.Value{}
+		}
+		for k, v := range in.Payload {
+			p.Payload[k] = v
+		}
+	}
+	return completed(), nil
+}
+
+func (s pointsService) Count(ctx context.Context, in *pb.CountPoints) (*pb.CountResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.collection(in.CollectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	var count uint64
+	for _, p := range c.points {
+		if matchFilter(in.Filter, p) {
+			count++
+		}
+	}
+	return &pb.CountResponse{Result: &pb.CountResult{Count: count}}, nil
+}
+
+func (s pointsService) Scroll(ctx context.Context, in *pb.ScrollPoints) (*pb.ScrollResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.collection(in.CollectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for id, p := range c.points {
+		if matchFilter(in.Filter, p) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	if in.Offset != nil {
+		offset := pointID(in.Offset)
+		i := sort.SearchStrings(ids, offset)
+		ids = ids[i:]
+	}
+
+	limit := 10
+	if in.Limit != nil {
+		limit = int(*in.Limit)
+	}
+
+	resp := &pb.ScrollResponse{}
+	for i, id := range ids {
+		if i == limit {
+			resp.NextPageOffset = c.points[id].Id
+			break
+		}
+		p := c.points[id]
+		resp.Result = append(resp.Result, &pb.RetrievedPoint{Id: p.Id, Payload: p.Payload})
+	}
+	return resp, nil
+}
+
+// Snapshots service
+
+func (s snapshotsService) Create(ctx context.Context, in *pb.CreateSnapshotRequest) (*pb.CreateSnapshotResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.collection(in.CollectionName); err != nil {
+		return nil, err
+	}
+
+	s.snapshots++
+	return &pb.CreateSnapshotResponse{
+		SnapshotDescription: &pb.SnapshotDescription{
+			Name:         fmt.Sprintf("%s-%d.snapshot", in.CollectionName, s.snapshots),
+			CreationTime: timestamppb.Now(),
+			Size:         1024,
+		},
+	}, nil
+}
+
+func completed() *pb.PointsOperationResponse {
+	return &pb.PointsOperationResponse{Result: &pb.UpdateResult{Status: pb.UpdateStatus_Completed}}
+}
+
+func pointID(id *pb.PointId) string {
+	if id == nil {
+		return ""
+	}
+	if uuid := id.GetUuid(); uuid != "" {
+		return uuid
+	}
+	return fmt.Sprintf("%d", id.GetNum())
+}
+
+// matchSelector reports whether a point is selected by id or filter
+func matchSelector(selector *pb.PointsSelector, id string, p *pb.PointStruct) bool {
+
+	if filter := selector.GetFilter(); filter != nil {
+		return matchFilter(filter, p)
+	}
+
+	for _, selected := range selector.GetPoints().GetIds() {
+		if pointID(selected) == id {
+			return true
+		}
+	}
+	return false
+}
+
+// matchFilter evaluates the keyword, integer and bool matches, is_empty,
+// has_id and nested filters of a filter against a point
+func matchFilter(f *pb.Filter, p *pb.PointStruct) bool {
+
+	if f == nil {
+		return true
+	}
+
+	for _, c := range f.Must {
+		if !matchCondition(c, p) {
+			return false
+		}
+	}
+
+	for _, c := range f.MustNot {
+		if matchCondition(c, p) {
+			return false
+		}
+	}
+
+	if len(f.Should) == 0 {
+		return true
+	}
+
+	for _, c := range f.Should {
+		if matchCondition(c, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchCondition(c *pb.Condition, p *pb.PointStruct) bool {
+
+	switch cond := c.ConditionOneOf.(type) {
+	case *pb.Condition_Filter:
+		return matchFilter(cond.Filter, p)
+	case *pb.Condition_HasId:
+		for _, id := range cond.HasId.HasId {
+			if pointID(id) == pointID(p.Id) {
+				return true
+			}
+		}
+		return false
+	case *pb.Condition_IsEmpty:
+		value, ok := p.Payload[cond.IsEmpty.Key]
+		if !ok || value.GetKind() == nil {
+			return true
+		}
+		_, null := value.GetKind().(*pb.Value_NullValue)
+		return null
+	case *pb.Condition_Field:
+		value, ok := p.Payload[cond.Field.Key]
+		if !ok {
+			return false
+		}
+		switch m := cond.Field.GetMatch().GetMatchValue().(type) {
+		case *pb.Match_Keyword:
+			return value.GetStringValue() == m.Keyword
+		case *pb.Match_Keywords:
+			for _, k := range m.Keywords.Strings {
+				if value.GetStringValue() == k {
+					return true
+				}
+			}
+			return false
+		case *pb.Match_Integer:
+			return value.GetIntegerValue() == m.Integer
+		case *pb.Match_Boolean:
+			return value.GetBoolValue() == m.Boolean
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,282 @@
+package qdrant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	rateLimitPrefix = "ratelimit/"
+
+	rateLimitWindow = time.Minute
+)
+
+// rateLimitState holds the tokens counted against a limit
+type rateLimitState struct {
+	// Issued are the issuance times within the last rateLimitWindow
+	Issued []time.Time `json:"issued,omitempty"`
+	// Expires are the expiry times of the tokens still valid
+	Expires []time.Time `json:"expires,omitempty"`
+}
+
+// rateLimit is a limit on the tokens of a role, or of an entity using
+// the role
+type rateLimit struct {
+	path      string
+	subject   string
+	perMinute int
+	maxValid  int
+}
+
+// roleRateLimits returns the limits of a role applying to a request of
+// the entity. Requests without an entity are only subject to the role
+// limits.
+func roleRateLimits(role *RoleParameters, entityID string) []rateLimit {
+
+	var limits []rateLimit
+
+	path := rateLimitPrefix + role.DBId + "/" + role.RoleId
+
+	if role.RateLimit > 0 || role.MaxValidTokens > 0 {
+		limits = append(limits, rateLimit{
+			path:      path,
+			subject:   fmt.Sprintf("role %q", role.RoleId),
+			perMinute: role.RateLimit,
+			maxValid:  role.MaxValidTokens,
+		})
+	}
+
+	if entityID != "" && (role.EntityRateLimit > 0 || role.EntityMaxValidTokens > 0) {
+		limits = append(limits, rateLimit{
+			path:      path + "/" + entityID,
+			subject:   fmt.Sprintf("entity %q on role %q", entityID, role.RoleId),
+			perMinute: role.EntityRateLimit,
+			maxValid:  role.EntityMaxValidTokens,
+		})
+	}
+
+	return limits
+}
+
+func validateRateLimits(role *RoleParameters) error {
+
+	if role.RateLimit < 0 || role.EntityRateLimit < 0 || role.MaxValidTokens < 0 || role.EntityMaxValidTokens < 0 {
+		return errors.New("rate limits must not be negative")
+	}
+
+	if role.Kind == roleKindStatic && (role.RateLimit > 0 || role.EntityRateLimit > 0 || role.MaxValidTokens > 0 || role.EntityMaxValidTokens > 0) {
+		return errors.New("static roles can not have rate limits")
+	}
+
+	return nil
+}
+
+// lockRateLimits serializes the issuance of a role's tokens on this
+// node. Nodes which can not write the counters forward the request to
+// the active node of the primary cluster, so the counters are shared by
+// all nodes and clusters.
+func (b *QdrantBackend) lockRateLimits(role *RoleParameters) (func(), error) {
+
+	if b.forwardWrites() {
+		return nil, logical.ErrReadOnly
+	}
+
+	lock := locksutil.LockForKey(b.rateLimitLocks, role.DBId+"/"+role.RoleId)
+	lock.Lock()
+
+	return lock.Unlock, nil
+}
+
+// forwardWrites reports whether writes to replicated storage have to
+// be forwarded from this node
+func (b *QdrantBackend) forwardWrites() bool {
+
+	sysView := b.System()
+	if sysView == nil {
+		return false
+	}
+
+	state := sysView.ReplicationState()
+
+	return state.HasState(consts.ReplicationPerformanceStandby) ||
+		(state.HasState(consts.ReplicationPerformanceSecondary) && !sysView.LocalMount())
+}
+
+// checkRateLimits fails with a rate_limited error if a token issued at
+// now would exceed one of the limits
+func checkRateLimits(ctx context.Context, storage logical.Storage, limits []rateLimit, now time.Time) error {
+
+	for _, limit := range limits {
+		state, err := readRateLimitState(ctx, storage, limit.path, now)
+		if err != nil {
+			return err
+		}
+
+		if limit.perMinute > 0 && len(state.Issued) >= limit.perMinute {
+			retry := state.Issued[len(state.Issued)-limit.perMinute].Add(rateLimitWindow).Sub(now)
+			return rateLimited(fmt.Sprintf("%s: limit of %d tokens per minute reached, retry in %s",
+				limit.subject, limit.perMinute, retry.Round(time.Second)))
+		}
+
+		if limit.maxValid > 0 && len(state.Expires) >= limit.maxValid {
+			retry := earliest(state.Expires).Sub(now)
+			return rateLimited(fmt.Sprintf("%s: limit of %d valid tokens reached, retry in %s",
+				limit.subject, limit.maxValid, retry.Round(time.Second)))
+		}
+	}
+
+	return nil
+}
+
+// recordRateLimits counts a token issued at now and valid until expiry
+// against the limits
+func recordRateLimits(ctx context.Context, storage logical.Storage, limits []rateLimit, now time.Time, expiry time.Time) error {
+
+	for _, limit := range limits {
+		state, err := readRateLimitState(ctx, storage, limit.path, now)
+		if err != nil {
+			return err
+		}
+
+		if limit.perMinute > 0 {
+			state.Issued = append(state.Issued, now.UTC())
+		}
+		if limit.maxValid > 0 {
+			state.Expires = append(state.Expires, expiry.UTC())
+		}
+
+		err = storeInStorage[rateLimitState](ctx, storage, limit.path, state)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readRateLimitState returns the counters of a limit without the
+// entries which no longer count at now
+func readRateLimitState(ctx context.Context, storage logical.Storage, path string, now time.Time) (*rateLimitState, error) {
+
+	stored, err := getFromStorage[rateLimitState](ctx, storage, path)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &rateLimitState{}
+	if stored == nil {
+		return state, nil
+	}
+
+	for _, t := range stored.Issued {
+		if now.Sub(t) < rateLimitWindow {
+			state.Issued = append(state.Issued, t)
+		}
+	}
+
+	for _, t := range stored.Expires {
+		if t.After(now) {
+			state.Expires = append(state.Expires, t)
+		}
+	}
+
+	return state, nil
+}
+
+// deleteRateLimits removes the counters of a role and its entities
+func deleteRateLimits(ctx context.Context, storage logical.Storage, dbId string, name string) error {
+
+	path := rateLimitPrefix + dbId + "/" + name
+
+	entities, err := storage.List(ctx, path+"/")
+	if err != nil {
+		return err
+	}
+
+	for _, entityID := range entities {
+		err = deleteFromStorage(ctx, storage, path+"/"+entityID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return deleteFromStorage(ctx, storage, path)
+}
+
+// moveRateLimits moves the counters of a role and its entities to the
+// new role name, so renaming a role does not reset its limits
+func moveRateLimits(ctx context.Context, storage logical.Storage, dbId string, name string, newName string) error {
+
+	path := rateLimitPrefix + dbId + "/" + name
+	newPath := rateLimitPrefix + dbId + "/" + newName
+
+	entities, err := storage.List(ctx, path+"/")
+	if err != nil {
+		return err
+	}
+
+	keys := []string{""}
+	for _, entityID := range entities {
+		keys = append(keys, "/"+entityID)
+	}
+
+	for _, key := range keys {
+		entry, err := storage.Get(ctx, path+key)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			continue
+		}
+
+		err = storage.Put(ctx, &logical.StorageEntry{Key: newPath + key, Value: entry.Value})
+		if err != nil {
+			return err
+		}
+
+		err = deleteFromStorage(ctx, storage, path+key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteInstanceRateLimits removes the counters of every role of the
+// instance, including roles deleted without their counters
+func deleteInstanceRateLimits(ctx context.Context, storage logical.Storage, dbId string) error {
+
+	names, err := storage.List(ctx, rateLimitPrefix+dbId+"/")
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		err = deleteRateLimits(ctx, storage, dbId, strings.TrimSuffix(name, "/"))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func earliest(times []time.Time) time.Time {
+
+	rval := times[0]
+	for _, t := range times[1:] {
+		if t.Before(rval) {
+			rval = t
+		}
+	}
+
+	return rval
+}
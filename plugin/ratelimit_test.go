@@ -0,0 +1,164 @@
+package qdrant
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimits(t *testing.T) {
+
+	b, reqStorage, _ := getTestBackendWithServer(t)
+	ctx := context.Background()
+
+	writeTestConfig(t, b, reqStorage, map[string]interface{}{"jwt_ttl": "1h"})
+
+	writeRole := func(name string, data map[string]interface{}) error {
+		data["claims"] = map[string]interface{}{"access": "r"}
+		_, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "role/instance1/" + name,
+			Storage:   reqStorage,
+			Data:      data,
+		})
+		return err
+	}
+
+	issue := func(name string, entityID string) error {
+		_, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "jwt/instance1/" + name,
+			Storage:   reqStorage,
+			EntityID:  entityID,
+		})
+		return err
+	}
+
+	assert.NoError(t, writeRole("ci", map[string]interface{}{"rate_limit": 3, "entity_rate_limit": 2}))
+
+	// per entity limit
+	assert.NoError(t, issue("ci", "entity-1"))
+	assert.NoError(t, issue("ci", "entity-1"))
+	err := issue("ci", "entity-1")
+	assertErrorCode(t, err, ErrCodeRateLimited, http.StatusTooManyRequests)
+	assert.ErrorContains(t, err, `entity "entity-1" on role "ci": limit of 2 tokens per minute reached`)
+
+	// per role limit
+	assert.NoError(t, issue("ci", "entity-2"))
+	err = issue("ci", "entity-3")
+	assertErrorCode(t, err, ErrCodeRateLimited, http.StatusTooManyRequests)
+	assert.ErrorContains(t, err, `role "ci": limit of 3 tokens per minute reached`)
+
+	// the window slides
+	state, err := readRateLimitState(ctx, reqStorage, "ratelimit/instance1/ci", time.Now().Add(rateLimitWindow))
+	assert.NoError(t, err)
+	assert.Empty(t, state.Issued)
+
+	// concurrently valid tokens
+	assert.NoError(t, writeRole("service", map[string]interface{}{"max_valid_tokens": 2}))
+	assert.NoError(t, issue("service", ""))
+	assert.NoError(t, issue("service", ""))
+	err = issue("service", "")
+	assertErrorCode(t, err, ErrCodeRateLimited, http.StatusTooManyRequests)
+	assert.ErrorContains(t, err, "limit of 2 valid tokens reached")
+
+	limits := roleRateLimits(&RoleParameters{DBId: "instance1", RoleId: "service", MaxValidTokens: 2}, "")
+	assert.NoError(t, checkRateLimits(ctx, reqStorage, limits, time.Now().Add(2*time.Hour)))
+
+	// invalid limits
+	err = writeRole("negative", map[string]interface{}{"rate_limit": -1})
+	assertErrorCode(t, err, ErrCodeInvalidRequest, http.StatusBadRequest)
+
+	err = writeRole("static", map[string]interface{}{"kind": "static", "rotation_period": "1h", "rate_limit": 1})
+	assertErrorCode(t, err, ErrCodeInvalidRequest, http.StatusBadRequest)
+
+	// deleting the role drops its counters
+	_, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "role/instance1/ci",
+		Storage:   reqStorage,
+	})
+	assert.NoError(t, err)
+
+	entries, err := reqStorage.List(ctx, "ratelimit/instance1/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"service"}, entries)
+
+	// renaming the role moves its counters
+	_, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "role/instance1/service/rename",
+		Storage:   reqStorage,
+		Data:      map[string]interface{}{"new_name": "worker"},
+	})
+	assert.NoError(t, err)
+
+	entries, err = reqStorage.List(ctx, "ratelimit/instance1/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"worker"}, entries)
+
+	err = issue("worker", "")
+	assertErrorCode(t, err, ErrCodeRateLimited, http.StatusTooManyRequests)
+
+	// a new role with the old name starts without counters
+	assert.NoError(t, writeRole("service", map[string]interface{}{"max_valid_tokens": 2}))
+	assert.NoError(t, issue("service", ""))
+
+	// deleting the instance drops every counter
+	err = storeInStorage(ctx, reqStorage, "ratelimit/instance1/gone/entity-1", &rateLimitState{})
+	assert.NoError(t, err)
+
+	_, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "config/instance1",
+		Storage:   reqStorage,
+	})
+	assert.NoError(t, err)
+
+	entries, err = reqStorage.List(ctx, "ratelimit/")
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestRateLimitsForwarded(t *testing.T) {
+
+	b, reqStorage, _ := getTestBackendWithServer(t)
+	ctx := context.Background()
+
+	writeTestConfig(t, b, reqStorage, nil)
+
+	for name, data := range map[string]map[string]interface{}{
+		"limited":   {"claims": map[string]interface{}{"access": "r"}, "rate_limit": 10},
+		"unlimited": {"claims": map[string]interface{}{"access": "r"}},
+	} {
+		_, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "role/instance1/" + name,
+			Storage:   reqStorage,
+			Data:      data,
+		})
+		assert.NoError(t, err)
+	}
+
+	// performance standbys forward limited issuance to the active node
+	b.System().(*logical.StaticSystemView).ReplicationStateVal = consts.ReplicationPerformanceStandby
+
+	_, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "jwt/instance1/limited",
+		Storage:   reqStorage,
+	})
+	assert.ErrorIs(t, err, logical.ErrReadOnly)
+
+	_, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "jwt/instance1/unlimited",
+		Storage:   reqStorage,
+	})
+	assert.NoError(t, err)
+}
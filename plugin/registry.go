@@ -0,0 +1,38 @@
+package qdrant
+
+import (
+	"context"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// RoleRegistry keeps the role names of an instance in its role
+// collection, which Qdrant checks the role claim of tokens against.
+// QdrantClient implements it over gRPC, other transports and test
+// doubles can be passed to FactoryWithRegistry.
+type RoleRegistry interface {
+	// EnsureRegistry creates the role collection if it is missing
+	EnsureRegistry(ctx context.Context, s logical.Storage, dbId string) error
+
+	// PutRoles adds or replaces the role names
+	PutRoles(ctx context.Context, s logical.Storage, dbId string, names []string) error
+
+	// DeleteRoles removes the role names
+	DeleteRoles(ctx context.Context, s logical.Storage, dbId string, names []string) error
+
+	// ListRoles returns the sorted role names
+	ListRoles(ctx context.Context, s logical.Storage, dbId string) ([]string, error)
+
+	// Health checks the instance is reachable
+	Health(ctx context.Context, s logical.Storage, dbId string) error
+
+	// Status checks the instance is reachable and describes the role
+	// collection
+	Status(ctx context.Context, s logical.Storage, dbId string) (*RegistryStatus, error)
+
+	// Bootstrap sets up the role collection from the spec and migrates
+	// it to the current layout
+	Bootstrap(ctx context.Context, s logical.Storage, dbId string, spec *RegistrySpec) (*BootstrapResult, error)
+}
+
+var _ RoleRegistry = (*QdrantClient)(nil)
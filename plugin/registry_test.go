@@ -0,0 +1,164 @@
+package qdrant
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+// memRegistry is a RoleRegistry keeping role names in memory
+type memRegistry struct {
+	roles map[string]map[string]bool
+}
+
+func (r *memRegistry) EnsureRegistry(ctx context.Context, s logical.Storage, dbId string) error {
+	if r.roles[dbId] == nil {
+		r.roles[dbId] = map[string]bool{}
+	}
+	return nil
+}
+
+func (r *memRegistry) PutRoles(ctx context.Context, s logical.Storage, dbId string, names []string) error {
+	r.EnsureRegistry(ctx, s, dbId)
+	for _, name := range names {
+		r.roles[dbId][name] = true
+	}
+	return nil
+}
+
+func (r *memRegistry) DeleteRoles(ctx context.Context, s logical.Storage, dbId string, names []string) error {
+	for _, name := range names {
+		delete(r.roles[dbId], name)
+	}
+	return nil
+}
+
+func (r *memRegistry) ListRoles(ctx context.Context, s logical.Storage, dbId string) ([]string, error) {
+	names := []string{}
+	for name := range r.roles[dbId] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (r *memRegistry) Health(ctx context.Context, s logical.Storage, dbId string) error {
+	return nil
+}
+
+func (r *memRegistry) Bootstrap(ctx context.Context, s logical.Storage, dbId string, spec *RegistrySpec) (*BootstrapResult, error) {
+	result := &BootstrapResult{Created: r.roles[dbId] == nil, IndexesCreated: []string{}, Layout: registryLayout}
+	r.EnsureRegistry(ctx, s, dbId)
+	return result, nil
+}
+
+func (r *memRegistry) Status(ctx context.Context, s logical.Storage, dbId string) (*RegistryStatus, error) {
+	return &RegistryStatus{
+		Version:         "memory",
+		Exists:          r.roles[dbId] != nil,
+		RoleIndexExists: r.roles[dbId] != nil,
+		Points:          uint64(len(r.roles[dbId])),
+	}, nil
+}
+
+func TestFactoryWithRegistry(t *testing.T) {
+
+	registry := &memRegistry{roles: map[string]map[string]bool{}}
+
+	config := logical.TestBackendConfig()
+	config.StorageView = new(logical.InmemStorage)
+	config.Logger = hclog.NewNullLogger()
+	config.System = logical.TestSystemView()
+
+	lb, err := FactoryWithRegistry(registry)(context.Background(), config)
+	assert.NoError(t, err)
+	b := lb.(*QdrantBackend)
+	reqStorage := config.StorageView
+
+	writeTestConfig(t, b, reqStorage, nil)
+
+	// bootstrap sets up the registry
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/instance1/bootstrap",
+		Storage:   reqStorage,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, true, resp.Data["created"])
+	assert.NotNil(t, registry.roles["instance1"])
+
+	for _, name := range []string{"read", "write"} {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "role/instance1/" + name,
+			Storage:   reqStorage,
+			Data:      map[string]interface{}{"claims": map[string]interface{}{"access": "r"}},
+		})
+		assert.NoError(t, err)
+		assert.False(t, resp.IsError())
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "role/instance1/write",
+		Storage:   reqStorage,
+	})
+	assert.NoError(t, err)
+	assert.False(t, resp.IsError())
+
+	names, err := registry.ListRoles(context.Background(), reqStorage, "instance1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"read"}, names)
+
+	// status describes the registry
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "status/instance1",
+		Storage:   reqStorage,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "memory", resp.Data["version"])
+	assert.Equal(t, true, resp.Data["sys_roles_exists"])
+}
+
+func TestQdrantClientRegistry(t *testing.T) {
+
+	b, reqStorage, srv := getTestBackendWithServer(t)
+	ctx := context.Background()
+
+	writeTestConfig(t, b, reqStorage, nil)
+
+	assert.NoError(t, b.registry.Health(ctx, reqStorage, "instance1"))
+
+	// a missing role collection holds no roles
+	names, err := b.registry.ListRoles(ctx, reqStorage, "instance1")
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+
+	assert.NoError(t, b.registry.EnsureRegistry(ctx, reqStorage, "instance1"))
+	assert.Equal(t, []string{SYS_ROLE_TABLE}, srv.Collections())
+
+	var put []string
+	for i := 0; i < rolePageSize+20; i++ {
+		put = append(put, fmt.Sprintf("role-%03d", i))
+	}
+	assert.NoError(t, b.registry.PutRoles(ctx, reqStorage, "instance1", put))
+
+	names, err = b.registry.ListRoles(ctx, reqStorage, "instance1")
+	assert.NoError(t, err)
+	assert.Equal(t, put, names)
+
+	assert.NoError(t, b.registry.DeleteRoles(ctx, reqStorage, "instance1", put[1:]))
+
+	names, err = b.registry.ListRoles(ctx, reqStorage, "instance1")
+	assert.NoError(t, err)
+	assert.Equal(t, put[:1], names)
+
+	writeTestConfig(t, b, reqStorage, map[string]interface{}{"sig_key": "another-very-long-256-bit-secret-key"})
+	assert.Error(t, b.registry.Health(ctx, reqStorage, "instance1"))
+}
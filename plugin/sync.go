@@ -0,0 +1,147 @@
+package qdrant
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	statePrefix = "state/"
+	syncPrefix  = "sync/"
+
+	syncStateSynced = "synced"
+	syncStateFailed = "failed"
+)
+
+// InstanceState records the last successful contact with an instance
+type InstanceState struct {
+	LastVerified *time.Time `json:"last_verified,omitempty"`
+	LastSynced   *time.Time `json:"last_synced,omitempty"`
+
+	// RegistryLayout is the sys_roles layout set up by the last
+	// bootstrap, zero for registries created by role writes
+	RegistryLayout int        `json:"registry_layout,omitempty"`
+	Bootstrapped   *time.Time `json:"bootstrapped,omitempty"`
+}
+
+// RoleSyncState records the last push of a role to the instance registry
+type RoleSyncState struct {
+	State      string     `json:"state"`
+	LastSynced *time.Time `json:"last_synced,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+func readInstanceState(ctx context.Context, storage logical.Storage, dbId string) (*InstanceState, error) {
+	return getFromStorage[InstanceState](ctx, storage, statePrefix+dbId)
+}
+
+func readRoleSync(ctx context.Context, storage logical.Storage, dbId string, role string) (*RoleSyncState, error) {
+	return getFromStorage[RoleSyncState](ctx, storage, syncPrefix+dbId+"/"+role)
+}
+
+func deleteRoleSync(ctx context.Context, storage logical.Storage, dbId string, role string) error {
+	return deleteFromStorage(ctx, storage, syncPrefix+dbId+"/"+role)
+}
+
+func deleteInstanceState(ctx context.Context, storage logical.Storage, dbId string) error {
+	return deleteFromStorage(ctx, storage, statePrefix+dbId)
+}
+
+// recordRoleSync stores the result of pushing roles to the instance.
+// A successful push also marks the instance as verified.
+func recordRoleSync(ctx context.Context, storage logical.Storage, dbId string, names []string, syncErr error) error {
+
+	now := time.Now().UTC()
+
+	for _, name := range names {
+		state, err := readRoleSync(ctx, storage, dbId, name)
+		if err != nil {
+			return err
+		}
+
+		if state == nil {
+			state = &RoleSyncState{}
+		}
+
+		if syncErr != nil {
+			state.State = syncStateFailed
+			state.LastError = syncErr.Error()
+		} else {
+			state.State = syncStateSynced
+			state.LastSynced = &now
+			state.LastError = ""
+		}
+
+		err = storeInStorage[RoleSyncState](ctx, storage, syncPrefix+dbId+"/"+name, state)
+		if err != nil {
+			return err
+		}
+	}
+
+	if syncErr != nil {
+		return nil
+	}
+
+	return recordInstanceVerified(ctx, storage, dbId, len(names) > 0)
+}
+
+// recordInstanceVerified marks a successful call to the instance
+func recordInstanceVerified(ctx context.Context, storage logical.Storage, dbId string, synced bool) error {
+
+	state, err := readInstanceState(ctx, storage, dbId)
+	if err != nil {
+		return err
+	}
+
+	if state == nil {
+		state = &InstanceState{}
+	}
+
+	now := time.Now().UTC()
+	state.LastVerified = &now
+	if synced {
+		state.LastSynced = &now
+	}
+
+	return storeInStorage[InstanceState](ctx, storage, statePrefix+dbId, state)
+}
+
+// registryBootstrapped reports whether the registry of the instance was
+// bootstrapped with the current layout
+func registryBootstrapped(ctx context.Context, storage logical.Storage, dbId string) (bool, error) {
+
+	state, err := readInstanceState(ctx, storage, dbId)
+	if err != nil {
+		return false, err
+	}
+
+	return state != nil && state.RegistryLayout >= registryLayout, nil
+}
+
+// recordRegistryLayout stores the registry layout of the instance, zero
+// after its connection changed
+func recordRegistryLayout(ctx context.Context, storage logical.Storage, dbId string, layout int) error {
+
+	state, err := readInstanceState(ctx, storage, dbId)
+	if err != nil {
+		return err
+	}
+
+	if state == nil {
+		if layout == 0 {
+			return nil
+		}
+		state = &InstanceState{}
+	}
+
+	state.RegistryLayout = layout
+	state.Bootstrapped = nil
+	if layout > 0 {
+		now := time.Now().UTC()
+		state.Bootstrapped = &now
+	}
+
+	return storeInStorage[InstanceState](ctx, storage, statePrefix+dbId, state)
+}
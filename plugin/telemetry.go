@@ -0,0 +1,207 @@
+package qdrant
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/sdk/logical"
+	"google.golang.org/grpc/status"
+)
+
+// Metric names are emitted through the global go-metrics sink and are
+// part of the plugin interface: keep them stable and documented in the
+// README.
+var (
+	// tokens issued by jwt/<instance>/<role>, labels instance and role
+	metricJWTIssued = []string{"qdrant", "jwt", "issued"}
+	// failed jwt/<instance>/<role> reads, labels instance, role and error_code
+	metricJWTIssueFailed = []string{"qdrant", "jwt", "issue_failed"}
+	// jwt/<instance>/<role> latency, label instance
+	metricJWTIssueTime = []string{"qdrant", "jwt", "issue_time"}
+
+	// Qdrant RPC latency, labels instance, method and code
+	metricRPCTime = []string{"qdrant", "rpc", "time"}
+	// failed Qdrant RPCs, labels instance, method and code
+	metricRPCErrors = []string{"qdrant", "rpc", "errors"}
+
+	// role names pushed to or removed from the registry, labels
+	// instance and operation
+	metricSyncRoles = []string{"qdrant", "sync", "roles"}
+	// failed registry pushes, labels instance, operation and error_code
+	metricSyncFailed = []string{"qdrant", "sync", "failed"}
+	// roles missing from the registry plus registry names without a
+	// role, label instance
+	metricSyncDrift = []string{"qdrant", "sync", "drift"}
+)
+
+const (
+	syncOperationPut    = "put"
+	syncOperationDelete = "delete"
+
+	// driftCheckInterval is the minimum time between drift checks of
+	// an instance by the periodic function
+	driftCheckInterval = 10 * time.Minute
+)
+
+// instrumentedRegistry emits sync metrics for the calls of a registry
+type instrumentedRegistry struct {
+	RoleRegistry
+}
+
+func (r instrumentedRegistry) PutRoles(ctx context.Context, s logical.Storage, dbId string, names []string) error {
+	err := r.RoleRegistry.PutRoles(ctx, s, dbId, names)
+	recordSync(dbId, syncOperationPut, len(names), err)
+	return err
+}
+
+func (r instrumentedRegistry) DeleteRoles(ctx context.Context, s logical.Storage, dbId string, names []string) error {
+	err := r.RoleRegistry.DeleteRoles(ctx, s, dbId, names)
+	recordSync(dbId, syncOperationDelete, len(names), err)
+	return err
+}
+
+func recordSync(dbId string, operation string, count int, err error) {
+
+	if count == 0 {
+		return
+	}
+
+	labels := []metrics.Label{
+		{Name: "instance", Value: dbId},
+		{Name: "operation", Value: operation},
+	}
+
+	if err != nil {
+		code, _ := classifyError(err)
+		metrics.IncrCounterWithLabels(metricSyncFailed, 1, append(labels, metrics.Label{Name: "error_code", Value: code}))
+		return
+	}
+
+	metrics.IncrCounterWithLabels(metricSyncRoles, float32(count), labels)
+}
+
+// recordIssuance emits the metrics of a jwt/<instance>/<role> read
+func recordIssuance(dbId string, role string, start time.Time, err error) {
+
+	// forwarded requests are counted by the active node
+	if errors.Is(err, logical.ErrReadOnly) {
+		return
+	}
+
+	labels := []metrics.Label{
+		{Name: "instance", Value: dbId},
+		{Name: "role", Value: role},
+	}
+
+	if err != nil {
+		code, _ := classifyError(err)
+		metrics.IncrCounterWithLabels(metricJWTIssueFailed, 1, append(labels, metrics.Label{Name: "error_code", Value: code}))
+		return
+	}
+
+	metrics.IncrCounterWithLabels(metricJWTIssued, 1, labels)
+	metrics.MeasureSinceWithLabels(metricJWTIssueTime, start, labels[:1])
+}
+
+// recordRPC emits the metrics of a Qdrant RPC
+func recordRPC(dbId string, method string, start time.Time, err error) {
+
+	labels := []metrics.Label{
+		{Name: "instance", Value: dbId},
+		{Name: "method", Value: strings.TrimPrefix(method, "/")},
+		{Name: "code", Value: status.Code(err).String()},
+	}
+
+	metrics.MeasureSinceWithLabels(metricRPCTime, start, labels)
+	if err != nil {
+		metrics.IncrCounterWithLabels(metricRPCErrors, 1, labels)
+	}
+}
+
+// RoleDrift lists the differences between the stored roles of an
+// instance and its registry
+type RoleDrift struct {
+	// Missing roles are stored in Vault but not in the registry
+	Missing []string `json:"missing"`
+	// Unknown names are in the registry without a stored role
+	Unknown []string `json:"unknown"`
+}
+
+// roleDrift compares the stored roles of an instance with its registry
+// and emits the drift gauge
+func (b *QdrantBackend) roleDrift(ctx context.Context, storage logical.Storage, dbId string) (*RoleDrift, error) {
+
+	stored, err := listRole(ctx, storage, dbId)
+	if err != nil {
+		return nil, err
+	}
+
+	registered, err := b.registry.ListRoles(ctx, storage, dbId)
+	if err != nil {
+		return nil, err
+	}
+
+	drift := &RoleDrift{
+		Missing: difference(stored, registered),
+		Unknown: difference(registered, stored),
+	}
+
+	metrics.SetGaugeWithLabels(metricSyncDrift, float32(len(drift.Missing)+len(drift.Unknown)), []metrics.Label{
+		{Name: "instance", Value: dbId},
+	})
+
+	return drift, nil
+}
+
+// checkDrift runs the drift check of every instance not checked within
+// driftCheckInterval. Unreachable instances are logged and skipped.
+func (b *QdrantBackend) checkDrift(ctx context.Context, storage logical.Storage) error {
+
+	configs, err := listConfig(ctx, storage)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	for _, dbId := range configs {
+		b.driftMutex.Lock()
+		due := now.Sub(b.driftChecked[dbId]) >= driftCheckInterval
+		if due {
+			b.driftChecked[dbId] = now
+		}
+		b.driftMutex.Unlock()
+
+		if !due {
+			continue
+		}
+
+		_, err := b.roleDrift(ctx, storage, dbId)
+		if err != nil {
+			b.Logger().Debug("drift check failed", "instance", dbId, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// difference returns the entries of a missing from b, in order
+func difference(a []string, b []string) []string {
+
+	seen := map[string]bool{}
+	for _, name := range b {
+		seen[name] = true
+	}
+
+	rval := []string{}
+	for _, name := range a {
+		if !seen[name] {
+			rval = append(rval, name)
+		}
+	}
+
+	return rval
+}
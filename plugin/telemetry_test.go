@@ -0,0 +1,114 @@
+package qdrant
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/migrx-io/vault-plugin-secrets-qdrant/plugin/qdranttest"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// getTestSink sends the global metrics to an in-memory sink for the
+// duration of the test
+func getTestSink(t *testing.T) *metrics.InmemSink {
+	t.Helper()
+
+	sink := metrics.NewInmemSink(time.Hour, time.Hour)
+
+	config := metrics.DefaultConfig("")
+	config.EnableHostname = false
+	config.EnableRuntimeMetrics = false
+	config.EnableServiceLabel = false
+
+	_, err := metrics.NewGlobal(config, sink)
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		metrics.NewGlobal(metrics.DefaultConfig(""), &metrics.BlackholeSink{})
+	})
+
+	return sink
+}
+
+func TestTelemetry(t *testing.T) {
+
+	sink := getTestSink(t)
+
+	b, reqStorage, srv := getTestBackendWithServer(t)
+	ctx := context.Background()
+
+	writeTestConfig(t, b, reqStorage, nil)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) error {
+		_, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   reqStorage,
+			Data:      data,
+		})
+		return err
+	}
+
+	claims := map[string]interface{}{"claims": map[string]interface{}{"access": "r"}}
+
+	assert.NoError(t, request(logical.CreateOperation, "role/instance1/read", claims))
+	assert.NoError(t, request(logical.ReadOperation, "jwt/instance1/read", nil))
+	assert.NoError(t, request(logical.ReadOperation, "jwt/instance1/read", nil))
+	assert.Error(t, request(logical.ReadOperation, "jwt/instance1/write", nil))
+
+	srv.FailOnce(qdranttest.MethodPointsUpsert, status.Error(codes.Unavailable, "upsert failed"))
+	assert.Error(t, request(logical.CreateOperation, "role/instance1/write", claims))
+
+	// roles written outside of the registry drift
+	err := storeInStorage(ctx, reqStorage, "role/instance1/write", &RoleParameters{DBId: "instance1", RoleId: "write"})
+	assert.NoError(t, err)
+
+	drift, err := b.roleDrift(ctx, reqStorage, "instance1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"write"}, drift.Missing)
+	assert.Empty(t, drift.Unknown)
+
+	data := sink.Data()
+	assert.Len(t, data, 1)
+
+	counters := data[0].Counters
+	gauges := data[0].Gauges
+	samples := data[0].Samples
+
+	assert.Equal(t, 2, counters["qdrant.jwt.issued;instance=instance1;role=read"].Count)
+	assert.Equal(t, 1, counters["qdrant.jwt.issue_failed;instance=instance1;role=write;error_code=not_found"].Count)
+	assert.Equal(t, 2, samples["qdrant.jwt.issue_time;instance=instance1"].Count)
+
+	assert.Equal(t, float64(1), counters["qdrant.sync.roles;instance=instance1;operation=put"].Sum)
+	assert.Equal(t, 1, counters["qdrant.sync.failed;instance=instance1;operation=put;error_code=qdrant_unavailable"].Count)
+	assert.Equal(t, float32(1), gauges["qdrant.sync.drift;instance=instance1"].Value)
+
+	assert.Equal(t, 1, counters["qdrant.rpc.errors;instance=instance1;method=qdrant.Points/Upsert;code=Unavailable"].Count)
+	assert.Equal(t, 1, samples["qdrant.rpc.time;instance=instance1;method=qdrant.Points/Upsert;code=OK"].Count)
+	assert.Equal(t, 1, samples["qdrant.rpc.time;instance=instance1;method=qdrant.Points/Upsert;code=Unavailable"].Count)
+}
+
+func TestCheckDrift(t *testing.T) {
+
+	b, reqStorage, srv := getTestBackendWithServer(t)
+	ctx := context.Background()
+
+	writeTestConfig(t, b, reqStorage, nil)
+
+	assert.NoError(t, b.checkDrift(ctx, reqStorage))
+	calls := srv.Calls(qdranttest.MethodCollectionExists)
+	assert.Equal(t, 1, calls)
+
+	// instances are checked once per interval
+	assert.NoError(t, b.checkDrift(ctx, reqStorage))
+	assert.Equal(t, calls, srv.Calls(qdranttest.MethodCollectionExists))
+
+	b.driftChecked["instance1"] = time.Now().Add(-driftCheckInterval)
+	assert.NoError(t, b.checkDrift(ctx, reqStorage))
+	assert.Equal(t, calls+1, srv.Calls(qdranttest.MethodCollectionExists))
+}
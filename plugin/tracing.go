@@ -0,0 +1,123 @@
+package qdrant
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	tracerName  = "github.com/migrx-io/vault-plugin-secrets-qdrant"
+	serviceName = "vault-plugin-secrets-qdrant"
+)
+
+// SetupTracing exports the plugin's spans via OTLP/gRPC to endpoint
+// (host:port) and propagates W3C trace context to Qdrant. The OTLP
+// exporter also reads the standard OTEL_EXPORTER_OTLP_* variables,
+// e.g. OTEL_EXPORTER_OTLP_INSECURE. The returned function flushes and
+// stops the exporter.
+func SetupTracing(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+
+	exporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(endpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", serviceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return provider.Shutdown, nil
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// HandleRequest traces the request. Without SetupTracing the global
+// tracer provider is a no-op.
+func (b *QdrantBackend) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+
+	ctx, span := tracer().Start(ctx, "vault "+string(req.Operation),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("vault.operation", string(req.Operation)),
+			attribute.String("vault.path", req.Path),
+		))
+	defer span.End()
+
+	resp, err := b.Backend.HandleRequest(ctx, req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+
+	return resp, err
+}
+
+// traceRPC starts the client span of a Qdrant RPC and adds its trace
+// context to the outgoing metadata
+func traceRPC(ctx context.Context, dbId string, method string) (context.Context, trace.Span) {
+
+	service, name, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+
+	ctx, span := tracer().Start(ctx, strings.TrimPrefix(method, "/"),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.service", service),
+			attribute.String("rpc.method", name),
+			attribute.String("qdrant.instance", dbId),
+		))
+
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	for k, v := range carrier {
+		ctx = metadata.AppendToOutgoingContext(ctx, k, v)
+	}
+
+	return ctx, span
+}
+
+// endRPC records the status of a Qdrant RPC and ends its span
+func endRPC(span trace.Span, err error) {
+
+	code := status.Code(err)
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, code.String())
+	}
+
+	span.End()
+}
+
+// rpcContext returns a context for Qdrant calls with the timeout. It
+// keeps the span of ctx but not its cancellation, so calls started by a
+// request are traced as its children.
+func rpcContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(trace.ContextWithSpan(context.Background(), trace.SpanFromContext(ctx)), timeout)
+}
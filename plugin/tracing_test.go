@@ -0,0 +1,96 @@
+package qdrant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/migrx-io/vault-plugin-secrets-qdrant/plugin/qdranttest"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// getTestExporter records the spans of the test in memory
+func getTestExporter(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+
+	return exporter
+}
+
+func TestTracing(t *testing.T) {
+
+	exporter := getTestExporter(t)
+
+	b, reqStorage, srv := getTestBackendWithServer(t)
+	ctx := context.Background()
+
+	writeTestConfig(t, b, reqStorage, nil)
+	exporter.Reset()
+
+	_, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/instance1/read",
+		Storage:   reqStorage,
+		Data:      map[string]interface{}{"claims": map[string]interface{}{"access": "r"}},
+	})
+	assert.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	assert.NotEmpty(t, spans)
+
+	// the request span is exported last, after its RPC children
+	root := spans[len(spans)-1]
+	assert.Equal(t, "vault create", root.Name)
+	assert.Equal(t, trace.SpanKindServer, root.SpanKind)
+
+	var upsert *tracetest.SpanStub
+	for i, span := range spans[:len(spans)-1] {
+		assert.Equal(t, root.SpanContext.SpanID(), span.Parent.SpanID(), span.Name)
+		assert.Equal(t, trace.SpanKindClient, span.SpanKind)
+		if span.Name == "qdrant.Points/Upsert" {
+			upsert = &spans[i]
+		}
+	}
+	assert.NotNil(t, upsert)
+
+	// the trace context reaches Qdrant
+	traceparent := srv.Metadata(qdranttest.MethodPointsUpsert).Get("traceparent")
+	assert.Len(t, traceparent, 1)
+	assert.Contains(t, traceparent[0], upsert.SpanContext.SpanID().String())
+
+	// failed RPCs mark their span and the request span
+	exporter.Reset()
+	srv.FailOnce(qdranttest.MethodPointsUpsert, status.Error(codes.Unavailable, "upsert failed"))
+
+	_, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/instance1/write",
+		Storage:   reqStorage,
+		Data:      map[string]interface{}{"claims": map[string]interface{}{"access": "r"}},
+	})
+	assert.Error(t, err)
+
+	for _, span := range exporter.GetSpans() {
+		if span.Name == "qdrant.Points/Upsert" || span.Name == "vault create" {
+			assert.Equal(t, otelcodes.Error, span.Status.Code, span.Name)
+		}
+	}
+}